@@ -0,0 +1,206 @@
+package struct2csv
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BundleFormat is the archive format of BundleWriter
+type BundleFormat int
+
+const (
+	BundleZip   BundleFormat = iota // zip archive
+	BundleTarGz                     // gzip compressed tar archive, each entry is held in memory to learn its size
+)
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	Rows      int                    `json:"rows"`
+	Columns   int                    `json:"columns"`
+	Generated time.Time              `json:"generated"`
+	Data      string                 `json:"data"`    // entry name of the CSV data
+	Mapping   string                 `json:"mapping"` // entry name of the header mapping
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+type bundleOptions struct {
+	format        BundleFormat
+	level         int // compression level of compress/flate, flate.NoCompression stores the entries
+	dataName      string
+	mappingName   string
+	manifestName  string
+	mappingFormat MappingFormat
+	converter     *StructConverter
+	now           func() time.Time
+}
+
+type BundleOption func(opts *bundleOptions)
+
+// WithBundleFormat sets the archive format, the default is BundleZip
+func WithBundleFormat(p BundleFormat) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.format = p
+	}
+}
+
+// WithBundleCompression sets the compress/flate level, e.g. flate.BestSpeed.
+// flate.NoCompression stores zip entries, a BundleTarGz stays gzip framed with stored blocks
+func WithBundleCompression(level int) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.level = level
+	}
+}
+
+// WithBundleNames sets the entry names, empty names keep the defaults
+// "data.csv", "mapping.csv" and "manifest.json"
+func WithBundleNames(data, mapping, manifest string) BundleOption {
+	return func(opts *bundleOptions) {
+		if data != "" {
+			opts.dataName = data
+		}
+		if mapping != "" {
+			opts.mappingName = mapping
+		}
+		if manifest != "" {
+			opts.manifestName = manifest
+		}
+	}
+}
+
+// WithBundleMappingFormat sets the layout of the mapping entry, the default is MappingRows
+func WithBundleMappingFormat(p MappingFormat) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.mappingFormat = p
+	}
+}
+
+// WithBundleConverter records the options of the converter which produced the KVs in the manifest
+func WithBundleConverter(p *StructConverter) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.converter = p
+	}
+}
+
+// WithBundleTime sets the clock of the generation time and the entry times
+func WithBundleTime(now func() time.Time) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.now = now
+	}
+}
+
+// BundleWriter writes the CSV data, its header mapping and a manifest into one archive.
+// zip entries are streamed, tar entries are buffered one at a time because a tar
+// header holds the size of its entry, so a BundleTarGz needs memory for the whole CSV
+type BundleWriter struct {
+	w    io.Writer
+	opts *bundleOptions
+}
+
+// NewBundleWriter returns new BundleWriter
+func NewBundleWriter(w io.Writer, opts ...BundleOption) *BundleWriter {
+	o := &bundleOptions{
+		format:        BundleZip,
+		level:         flate.DefaultCompression,
+		dataName:      "data.csv",
+		mappingName:   "mapping.csv",
+		manifestName:  "manifest.json",
+		mappingFormat: MappingRows,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &BundleWriter{w: w, opts: o}
+}
+
+// WriteBundle writes the whole archive, the values of kvs are consumed like by CSVWriter.WriteCSV
+func (b *BundleWriter) WriteBundle(kvs *KVs) error {
+	now := b.opts.now()
+	manifest := Manifest{
+		Rows:      kvs.Rows(),
+		Columns:   len(kvs.GetMapping()),
+		Generated: now,
+		Data:      b.opts.dataName,
+		Mapping:   b.opts.mappingName,
+	}
+	if b.opts.converter != nil {
+		manifest.Options = b.opts.converter.describeOptions()
+	}
+
+	entries := []struct {
+		name  string
+		write func(w io.Writer) error
+	}{
+		{b.opts.dataName, func(w io.Writer) error { return NewCSVWriter(w).WriteCSV(kvs) }},
+		{b.opts.mappingName, func(w io.Writer) error { return WriteMappingAs(w, kvs, b.opts.mappingFormat) }},
+		{b.opts.manifestName, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(manifest)
+		}},
+	}
+
+	switch b.opts.format {
+	case BundleZip:
+		zw := zip.NewWriter(b.w)
+		method := zip.Deflate
+		if b.opts.level == flate.NoCompression {
+			method = zip.Store
+		} else {
+			level := b.opts.level
+			zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(w, level)
+			})
+		}
+
+		for _, e := range entries {
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Modified: now, Method: method})
+			if err != nil {
+				return err
+			}
+			if err := e.write(f); err != nil {
+				return fmt.Errorf("WriteBundle: %s: %w", e.name, err)
+			}
+		}
+		return zw.Close()
+	case BundleTarGz:
+		gw, err := gzip.NewWriterLevel(b.w, b.opts.level)
+		if err != nil {
+			return err
+		}
+		tw := tar.NewWriter(gw)
+
+		var buf bytes.Buffer
+		for _, e := range entries {
+			buf.Reset()
+			if err := e.write(&buf); err != nil {
+				return fmt.Errorf("WriteBundle: %s: %w", e.name, err)
+			}
+			if err := tw.WriteHeader(&tar.Header{
+				Name:    e.name,
+				Mode:    0644,
+				Size:    int64(buf.Len()),
+				ModTime: now,
+			}); err != nil {
+				return err
+			}
+			if _, err := tw.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gw.Close()
+	default:
+		return fmt.Errorf("WriteBundle: unknown format %d", b.opts.format)
+	}
+}
@@ -0,0 +1,127 @@
+package struct2csv
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBundleWriter_WriteBundle(t *testing.T) {
+	generated := time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+
+	for _, format := range []BundleFormat{BundleZip, BundleTarGz} {
+		conv, _ := NewStructConverter(NewHeaderAutoIncrementConv(), WithExclude("/B3"))
+		kvs, err := conv.Convert([]testStruct{{A1: 1, B1: []int{2}}, {A1: 3}})
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+
+		var b bytes.Buffer
+		bundle := NewBundleWriter(&b, WithBundleFormat(format), WithBundleNames("rows.csv", "", ""),
+			WithBundleConverter(conv), WithBundleTime(func() time.Time { return generated }))
+		if err := bundle.WriteBundle(kvs); err != nil {
+			t.Fatalf("WriteBundle(%d) error = %v", format, err)
+		}
+
+		entries := readBundle(t, format, b.Bytes())
+		want := map[string]string{
+			"rows.csv":    "1,2\n1,2\n3,\n",
+			"mapping.csv": "/A1,/B1/0\n1,2\n",
+		}
+		for name, content := range want {
+			if entries[name] != content {
+				t.Errorf("WriteBundle(%d) %s = %q, want %q", format, name, entries[name], content)
+			}
+		}
+
+		manifest := Manifest{}
+		if err := json.Unmarshal([]byte(entries["manifest.json"]), &manifest); err != nil {
+			t.Fatalf("WriteBundle(%d) manifest error = %v", format, err)
+		}
+		wantManifest := Manifest{
+			Rows:      2,
+			Columns:   2,
+			Generated: generated,
+			Data:      "rows.csv",
+			Mapping:   "mapping.csv",
+			Options: map[string]interface{}{
+				"header_converter": "*struct2csv.HeaderAutoIncrementConv",
+				"proto_naming":     float64(0),
+				"exclude":          []interface{}{"/B3"},
+			},
+		}
+		if !reflect.DeepEqual(manifest, wantManifest) {
+			t.Errorf("WriteBundle(%d) manifest = %+v, want %+v", format, manifest, wantManifest)
+		}
+	}
+}
+
+func TestBundleWriter_NoCompression(t *testing.T) {
+	for _, format := range []BundleFormat{BundleZip, BundleTarGz} {
+		conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+		kvs, err := conv.Convert([]testStruct{{A1: 1}})
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+
+		var b bytes.Buffer
+		bundle := NewBundleWriter(&b, WithBundleFormat(format), WithBundleCompression(flate.NoCompression))
+		if err := bundle.WriteBundle(kvs); err != nil {
+			t.Fatalf("WriteBundle(%d) error = %v", format, err)
+		}
+
+		// the data is stored as is, and the tar keeps its gzip framing
+		if !bytes.Contains(b.Bytes(), []byte("1\n1\n")) {
+			t.Errorf("WriteBundle(%d) did not store the data uncompressed", format)
+		}
+		if got := readBundle(t, format, b.Bytes())["data.csv"]; got != "1\n1\n" {
+			t.Errorf("WriteBundle(%d) data.csv = %q", format, got)
+		}
+	}
+}
+
+func readBundle(t *testing.T, format BundleFormat, b []byte) map[string]string {
+	t.Helper()
+
+	entries := map[string]string{}
+	if format == BundleZip {
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(rc)
+			rc.Close()
+			entries[f.Name] = string(content)
+		}
+		return entries
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		entries[h.Name] = string(content)
+	}
+}
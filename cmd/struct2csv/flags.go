@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"struct2csv"
+)
+
+type config struct {
+	input           string // file name, "" or "-" is stdin
+	format          string
+	to              string
+	sqlDialect      string
+	sqlTable        string
+	maxWidth        int
+	widths          stringList
+	groupHeader     bool
+	header          string
+	dict            string
+	mapping         string
+	prevMapping     string
+	mappingFormat   string
+	bundle          string
+	delimiter       string
+	crlf            bool
+	alwaysQuote     bool
+	bom             bool
+	noHeader        bool
+	formulaEscape   string
+	numericColumns  stringList
+	decode          string
+	output          string
+	desc            string
+	message         string
+	pathStyle       string
+	pathLabel       string
+	sliceMode       string
+	order           string
+	emitUnpopulated bool
+	typeColumn      bool
+	anyTypeColumn   bool
+	include         stringList
+	exclude         stringList
+
+	stdout io.Writer // where "-o -" writes
+}
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// errUsage marks errors in the command line, the command exits with 2 for them
+type errUsage struct {
+	err error
+}
+
+func (e errUsage) Error() string {
+	return e.err.Error()
+}
+
+func printUsage(w io.Writer) {
+	fs := newFlagSet(&config{})
+	fs.SetOutput(w)
+	fmt.Fprintln(w, "usage: struct2csv [flags] [file]")
+	fs.PrintDefaults()
+}
+
+func newFlagSet(cfg *config) *flag.FlagSet {
+	fs := flag.NewFlagSet("struct2csv", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&cfg.format, "format", "json", "input format: json, ndjson, proto (length-delimited binary) or protojson")
+	fs.StringVar(&cfg.to, "to", "csv", "output format: csv, jsonl, xlsx, parquet, sql, markdown, html, fixed, ltsv, or table and vertical for a terminal")
+	fs.StringVar(&cfg.sqlDialect, "sql-dialect", "postgresql", "dialect of -to sql: postgresql, mysql or sqlite")
+	fs.StringVar(&cfg.sqlTable, "sql-table", "data", "table name of -to sql")
+	fs.IntVar(&cfg.maxWidth, "max-width", 0, "cut cells of -to markdown, html, table and vertical longer than this, 0 keeps them whole")
+	fs.Var(&cfg.widths, "width", "path=width of a -to fixed column, longer values are cut, other widths fit the data, repeatable")
+	fs.BoolVar(&cfg.groupHeader, "group-header", false, "write one header row per path level for -to csv and html")
+	fs.StringVar(&cfg.header, "header", "original", "header converter: original, autoinc, snake, camel, title or kebab")
+	fs.StringVar(&cfg.dict, "dict", "", "csv of path,label rows renaming headers, other paths use -header")
+	fs.StringVar(&cfg.mapping, "mapping", "", "write the header mapping csv to this file")
+	fs.StringVar(&cfg.mappingFormat, "mapping-format", "rows", "mapping layout: rows, columns or json")
+	fs.StringVar(&cfg.delimiter, "delimiter", ",", "csv field delimiter, a single character or tab")
+	fs.BoolVar(&cfg.crlf, "crlf", false, "end csv lines with \\r\\n")
+	fs.BoolVar(&cfg.alwaysQuote, "quote-all", false, "quote every csv field")
+	fs.BoolVar(&cfg.bom, "bom", false, "start the csv with a UTF-8 byte order mark for Excel")
+	fs.BoolVar(&cfg.noHeader, "no-header", false, "omit the csv header row")
+	fs.StringVar(&cfg.formulaEscape, "formula-escape", "", "prefix string values a spreadsheet would run as formula, e.g. \"'\"")
+	fs.Var(&cfg.numericColumns, "numeric-column", "path pattern of a column whose numeric strings are not escaped, repeatable")
+	fs.StringVar(&cfg.bundle, "bundle", "", "write data, mapping and manifest as one archive to -o: zip or tgz")
+	fs.StringVar(&cfg.prevMapping, "prev-mapping", "", "keep the autoinc IDs of this mapping csv, -mapping then gets every known path and may be the same file")
+	fs.StringVar(&cfg.output, "o", "-", "output csv file, - is stdout")
+	fs.StringVar(&cfg.desc, "desc", "", "FileDescriptorSet of the protobuf schema, required by proto and protojson")
+	fs.StringVar(&cfg.message, "message", "", "full name of the protobuf message, required by proto and protojson")
+	fs.StringVar(&cfg.pathStyle, "path-style", "text", "path token of a protobuf field: text, json, full or number")
+	fs.StringVar(&cfg.pathLabel, "path-label", "", "full name of a string field option of the descriptor set, e.g. pkg.column, whose value replaces the path token of the fields carrying it")
+	fs.StringVar(&cfg.sliceMode, "slice-mode", "auto", "rows of a top level array: auto makes a row per element of an array of objects and one row of any other array, rows a row per element")
+	fs.StringVar(&cfg.order, "order", "", "header order: sorted or insertion, the default is insertion for json and ndjson and sorted otherwise")
+	fs.BoolVar(&cfg.emitUnpopulated, "emit-unpopulated", false, "emit columns for unpopulated protobuf fields")
+	fs.BoolVar(&cfg.typeColumn, "type-column", false, "add $type columns for protobuf oneofs")
+	fs.BoolVar(&cfg.anyTypeColumn, "any-type-column", false, "add $type columns for unpacked protobuf Any fields")
+	fs.StringVar(&cfg.decode, "decode", "", "instead of converting, read an encoded csv written with the same -delimiter and -group-header and rewrite its header to paths with this mapping csv")
+	fs.Var(&cfg.include, "include", "keep only columns below paths matching this pattern, e.g. /items/*/price or /meta/**, repeatable")
+	fs.Var(&cfg.exclude, "exclude", "drop columns below paths matching this pattern, repeatable")
+	return fs
+}
+
+// parseArgs parses the arguments after the program name, its errors are errUsage
+// except for flag.ErrHelp
+func parseArgs(args []string) (config, error) {
+	var cfg config
+	fs := newFlagSet(&cfg)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cfg, err
+		}
+		return cfg, errUsage{err}
+	}
+	if fs.NArg() > 1 {
+		return cfg, errUsage{fmt.Errorf("one input file expected, got %d", fs.NArg())}
+	}
+	cfg.input = fs.Arg(0)
+	return cfg, nil
+}
+
+// converterOptions returns the options of the flags, -path-label is added by convert
+// once the descriptor set holding it is read
+func converterOptions(cfg config) ([]struct2csv.Option, error) {
+	opts := []struct2csv.Option{
+		struct2csv.WithRowSize(256),
+		struct2csv.WithEmitUnpopulated(cfg.emitUnpopulated),
+		struct2csv.WithTypeColumn(cfg.typeColumn),
+		struct2csv.WithAnyTypeColumn(cfg.anyTypeColumn),
+		struct2csv.WithInclude(cfg.include...),
+		struct2csv.WithExclude(cfg.exclude...),
+	}
+
+	switch cfg.pathStyle {
+	case "text":
+		opts = append(opts, struct2csv.WithProtoFieldNaming(struct2csv.ProtoTextName))
+	case "json":
+		opts = append(opts, struct2csv.WithProtoFieldNaming(struct2csv.ProtoJSONName))
+	case "full":
+		opts = append(opts, struct2csv.WithProtoFieldNaming(struct2csv.ProtoFullName))
+	case "number":
+		opts = append(opts, struct2csv.WithProtoFieldNaming(struct2csv.ProtoFieldNumber))
+	default:
+		return nil, fmt.Errorf("unknown path style %q", cfg.pathStyle)
+	}
+
+	switch cfg.sliceMode {
+	case "auto":
+		opts = append(opts, struct2csv.WithIsObjArray(false))
+	case "rows":
+		opts = append(opts, struct2csv.WithIsObjArray(true))
+	default:
+		return nil, fmt.Errorf("unknown slice mode %q", cfg.sliceMode)
+	}
+
+	switch cfg.order {
+	case "":
+	case "sorted":
+		opts = append(opts, struct2csv.WithInsertionOrder(false))
+	case "insertion":
+		opts = append(opts, struct2csv.WithInsertionOrder(true))
+	default:
+		return nil, fmt.Errorf("unknown header order %q", cfg.order)
+	}
+
+	return opts, nil
+}
+
+func mappingFormat(name string) (struct2csv.MappingFormat, error) {
+	switch name {
+	case "rows":
+		return struct2csv.MappingRows, nil
+	case "columns":
+		return struct2csv.MappingColumns, nil
+	case "json":
+		return struct2csv.MappingJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown mapping format %q", name)
+	}
+}
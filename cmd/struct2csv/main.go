@@ -0,0 +1,204 @@
+// Command struct2csv converts JSON, NDJSON or protobuf input into CSV, JSON Lines,
+// XLSX, Parquet, SQL, Markdown, HTML, fixed width records, LTSV or a terminal table
+// with the same flattening rules as the struct2csv library.
+//
+// Usage:
+//
+//	struct2csv [flags] [file]
+//
+// the input is read from file, or from stdin if file is omitted or "-".
+// protobuf input needs the descriptor set of its schema, e.g.
+//
+//	protoc --include_imports --descriptor_set_out=event.desc event.proto
+//	struct2csv -format proto -desc event.desc -message pkg.Event events.bin
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"struct2csv"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage(os.Stderr)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "struct2csv:", err)
+	if errors.As(err, &errUsage{}) {
+		printUsage(os.Stderr)
+	}
+	os.Exit(exitCode(err))
+}
+
+// exitCode returns the exit status of the error of run
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &errUsage{}):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// run runs the command with the arguments after the program name, "-o -" writes to stdout
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	cfg, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	cfg.stdout = stdout
+
+	in := stdin
+	if cfg.input != "" && cfg.input != "-" {
+		f, err := os.Open(cfg.input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	in = bufio.NewReader(in)
+
+	if cfg.decode != "" {
+		return decodeCSV(cfg, in)
+	}
+
+	autoInc, err := newAutoIncrementConverter(cfg)
+	if err != nil {
+		return err
+	}
+	conv, results, err := convert(cfg, in, autoInc)
+	if err != nil {
+		return err
+	}
+	return writeResults(cfg, conv, results, autoInc)
+}
+
+// convert converts the input in the -format
+func convert(cfg config, in io.Reader, autoInc *struct2csv.HeaderAutoIncrementConv) (*struct2csv.StructConverter, *struct2csv.KVs, error) {
+	headerConv, err := newHeaderConverter(cfg.header, cfg.dict, autoInc)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err := converterOptions(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch cfg.format {
+	case "json", "ndjson":
+		if cfg.pathLabel != "" {
+			return nil, nil, errors.New("-path-label needs protobuf input")
+		}
+		conv, err := struct2csv.NewStructConverter(headerConv, append(opts, struct2csv.WithResultCap(0))...)
+		if err != nil {
+			return nil, nil, err
+		}
+		var results *struct2csv.KVs
+		if cfg.format == "json" {
+			results, err = conv.ConvertJSON(in)
+		} else {
+			results, err = conv.ConvertNDJSON(in)
+		}
+		return conv, results, err
+	case "proto", "protojson":
+		msgs, set, err := decodeProto(cfg, in)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, struct2csv.WithResultCap(len(msgs)), struct2csv.WithAnyResolver(set.Types))
+		if cfg.pathLabel != "" {
+			xt, err := pathLabel(set, cfg.pathLabel)
+			if err != nil {
+				return nil, nil, err
+			}
+			opts = append(opts, struct2csv.WithProtoFieldLabel(xt))
+		}
+		conv, err := struct2csv.NewStructConverter(headerConv, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		results, err := conv.Convert(msgs)
+		return conv, results, err
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q", cfg.format)
+	}
+}
+
+func decodeCSV(cfg config, in io.Reader) error {
+	f, err := os.Open(cfg.decode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	mapping, err := struct2csv.ReadMapping(f)
+	if err != nil {
+		return err
+	}
+	csvOpts, err := csvOptions(cfg)
+	if err != nil {
+		return err
+	}
+	return writeFile(cfg, cfg.output, func(w io.Writer) error {
+		return mapping.DecodeCSV(w, in, csvOpts...)
+	})
+}
+
+func newAutoIncrementConverter(cfg config) (*struct2csv.HeaderAutoIncrementConv, error) {
+	if cfg.prevMapping == "" {
+		return struct2csv.NewHeaderAutoIncrementConv(), nil
+	}
+	if cfg.header != "autoinc" {
+		return nil, errors.New("-prev-mapping needs -header autoinc")
+	}
+
+	f, err := os.Open(cfg.prevMapping)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return struct2csv.NewHeaderAutoIncrementConvFromMapping(f)
+}
+
+func newHeaderConverter(name, dict string, autoInc *struct2csv.HeaderAutoIncrementConv) (struct2csv.HeaderConverter, error) {
+	var conv struct2csv.HeaderConverter
+	switch name {
+	case "original":
+		conv = struct2csv.NewHeaderOriginalStringConv()
+	case "autoinc":
+		conv = autoInc
+	case "snake":
+		conv = struct2csv.NewHeaderCaseConv(struct2csv.SnakeCase, struct2csv.CollisionSuffix)
+	case "camel":
+		conv = struct2csv.NewHeaderCaseConv(struct2csv.CamelCase, struct2csv.CollisionSuffix)
+	case "title":
+		conv = struct2csv.NewHeaderCaseConv(struct2csv.TitleCase, struct2csv.CollisionSuffix)
+	case "kebab":
+		conv = struct2csv.NewHeaderCaseConv(struct2csv.KebabCase, struct2csv.CollisionSuffix)
+	default:
+		return nil, fmt.Errorf("unknown header converter %q", name)
+	}
+
+	if dict == "" {
+		return conv, nil
+	}
+	f, err := os.Open(dict)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return struct2csv.NewHeaderDictionaryConvFromCSV(f, conv)
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestRun(t *testing.T) {
+	const input = `[{"id": 1, "name": "a", "tags": ["x"]}, {"id": 2, "name": "b"}]`
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		want     string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:  "json keeps key order",
+			stdin: input,
+			want:  "/id,/name,/tags/0\n1,a,x\n2,b,\n",
+		},
+		{
+			name:  "sorted snake headers",
+			args:  []string{"-order", "sorted", "-header", "snake"},
+			stdin: `{"b": 1, "a": {"C": 2}}`,
+			want:  "a_c,b\n2,1\n",
+		},
+		{
+			name:  "ndjson to ltsv",
+			args:  []string{"-format", "ndjson", "-to", "ltsv", "-header", "snake"},
+			stdin: "{\"id\": 1}\n{\"id\": 2, \"ok\": true}\n",
+			want:  "id:1\nid:2\tok:true\n",
+		},
+		{
+			name: "input file argument",
+			args: []string{"-no-header", "-delimiter", ";", filepath.Join("testdata", "users.json")},
+			want: "1;alice\n2;bob\n",
+		},
+		{
+			name:  "top level array as one row",
+			stdin: `[[1, 2], [3]]`,
+			want:  "/0/0,/0/1,/1/0\n1,2,3\n",
+		},
+		{
+			name:  "top level array as rows",
+			args:  []string{"-slice-mode", "rows"},
+			stdin: `[[1, 2], [3]]`,
+			want:  "/0,/1\n1,2\n3,\n",
+		},
+		{
+			name:     "unknown slice mode",
+			args:     []string{"-slice-mode", "cols"},
+			wantCode: 1,
+			wantErr:  `unknown slice mode "cols"`,
+		},
+		{
+			name:     "unknown path style",
+			args:     []string{"-path-style", "camel"},
+			wantCode: 1,
+			wantErr:  `unknown path style "camel"`,
+		},
+		{
+			name:     "path label of json",
+			args:     []string{"-path-label", "pkg.column"},
+			stdin:    input,
+			wantCode: 1,
+			wantErr:  "-path-label needs protobuf input",
+		},
+		{
+			name:     "unknown input format",
+			args:     []string{"-format", "xml"},
+			wantCode: 1,
+			wantErr:  `unknown format "xml"`,
+		},
+		{
+			name:     "unknown output format",
+			args:     []string{"-to", "pdf"},
+			stdin:    input,
+			wantCode: 1,
+			wantErr:  `unknown output format "pdf"`,
+		},
+		{
+			name:     "invalid json",
+			stdin:    `[{"id": 1}`,
+			wantCode: 1,
+			wantErr:  "ConvertJSON",
+		},
+		{
+			name:     "proto without descriptor set",
+			args:     []string{"-format", "proto"},
+			wantCode: 1,
+			wantErr:  "-desc and -message are required",
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"-nope"},
+			wantCode: 2,
+			wantErr:  "flag provided but not defined",
+		},
+		{
+			name:     "bad flag value",
+			args:     []string{"-max-width", "wide"},
+			wantCode: 2,
+			wantErr:  "invalid value",
+		},
+		{
+			name:     "two input files",
+			args:     []string{"a.json", "b.json"},
+			wantCode: 2,
+			wantErr:  "one input file expected",
+		},
+		{
+			name:     "missing input file",
+			args:     []string{filepath.Join("testdata", "missing.json")},
+			wantCode: 1,
+			wantErr:  "missing.json",
+		},
+		{
+			name:     "help",
+			args:     []string{"-h"},
+			wantCode: 0,
+			wantErr:  "help requested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tt.args, strings.NewReader(tt.stdin), &out)
+			if code := exitCode(err); code != tt.wantCode {
+				t.Fatalf("exitCode() = %d, want %d, error %v", code, tt.wantCode, err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("run() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_OutputAndMappingFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.csv")
+	mapping := filepath.Join(dir, "mapping.csv")
+
+	args := []string{"-header", "autoinc", "-o", data, "-mapping", mapping, "-mapping-format", "columns"}
+	var out bytes.Buffer
+	if err := run(args, strings.NewReader(`{"b": "x", "a": 1}`), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("run() wrote %q to stdout", out.String())
+	}
+
+	assertFile(t, data, "1,2\nx,1\n")
+	assertFile(t, mapping, "path,code,type\n/b,1,string\n/a,2,number\n")
+
+	// the data is decoded back to paths with the mapping
+	out.Reset()
+	f, err := os.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := run([]string{"-decode", mapping}, f, &out); err != nil {
+		t.Fatalf("run(-decode) error = %v", err)
+	}
+	if want := "/b,/a\nx,1\n"; out.String() != want {
+		t.Errorf("run(-decode) wrote %q, want %q", out.String(), want)
+	}
+	// a csv with another delimiter is decoded with the same -delimiter
+	args = []string{"-header", "autoinc", "-delimiter", ";", "-o", data, "-mapping", mapping}
+	if err := run(args, strings.NewReader(`{"b": "x;y", "a": 1}`), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	out.Reset()
+	f2, err := os.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if err := run([]string{"-decode", mapping, "-delimiter", ";"}, f2, &out); err != nil {
+		t.Fatalf("run(-decode -delimiter) error = %v", err)
+	}
+	if want := "/b;/a\n\"x;y\";1\n"; out.String() != want {
+		t.Errorf("run(-decode -delimiter) wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_ProtoJSONWithWellKnownAny(t *testing.T) {
+	// message Event { string id = 1; google.protobuf.Any payload = 2; }
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("event.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("id"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					JsonName: proto.String("id"),
+				},
+				{
+					Name:     proto.String("payload"),
+					Number:   proto.Int32(2),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".google.protobuf.Any"),
+					JsonName: proto.String("payload"),
+				},
+			},
+		}},
+	}
+	desc := writeDescriptorSet(t, file)
+
+	input := `{"id": "e1", "payload": {"@type": "type.googleapis.com/google.protobuf.Int64Value", "value": "7"}}`
+	var out bytes.Buffer
+	args := []string{"-format", "protojson", "-desc", desc, "-message", "test.Event"}
+	if err := run(args, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if want := "/id,/payload/value\ne1,7\n"; out.String() != want {
+		t.Errorf("run() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_ProtoPathStyle(t *testing.T) {
+	// extend google.protobuf.FieldOptions { string column = 50000; }
+	// message Event { string event_id = 1 [(column) = "id"]; int64 created_at = 2; }
+	var label []byte
+	label = protowire.AppendTag(label, 50000, protowire.BytesType)
+	label = protowire.AppendString(label, "id")
+	eventIDOpts := &descriptorpb.FieldOptions{}
+	eventIDOpts.ProtoReflect().SetUnknown(label)
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("event.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Syntax:     proto.String("proto3"),
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("column"),
+			Number:   proto.Int32(50000),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+			JsonName: proto.String("column"),
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("event_id"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					JsonName: proto.String("eventId"),
+					Options:  eventIDOpts,
+				},
+				{
+					Name:     proto.String("created_at"),
+					Number:   proto.Int32(2),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+					JsonName: proto.String("createdAt"),
+				},
+			},
+		}},
+	}
+	desc := writeDescriptorSet(t, file)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "text names",
+			want: "/created_at,/event_id\n7,e1\n",
+		},
+		{
+			name: "json names",
+			args: []string{"-path-style", "json"},
+			want: "/createdAt,/eventId\n7,e1\n",
+		},
+		{
+			name: "json names and label",
+			args: []string{"-path-style", "json", "-path-label", "test.column"},
+			want: "/createdAt,/id\n7,e1\n",
+		},
+		{
+			name:    "unknown label",
+			args:    []string{"-path-label", "test.name"},
+			wantErr: "path label test.name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"-format", "protojson", "-desc", desc, "-message", "test.Event"}, tt.args...)
+			err := run(args, strings.NewReader(`{"eventId": "e1", "createdAt": "7"}`), &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("run() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+// writeDescriptorSet writes a FileDescriptorSet of file to a temporary file and returns its name
+func writeDescriptorSet(t *testing.T, file *descriptorpb.FileDescriptorProto) string {
+	t.Helper()
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := filepath.Join(t.TempDir(), "event.desc")
+	if err := os.WriteFile(desc, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return desc
+}
+
+func assertFile(t *testing.T, name, want string) {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(name), b, want)
+	}
+}
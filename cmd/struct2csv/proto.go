@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	// register the well-known types, descriptor sets rarely include them
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/sourcecontextpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/typepb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"struct2csv"
+)
+
+func decodeProto(cfg config, in io.Reader) ([]proto.Message, *struct2csv.DescriptorSet, error) {
+	if cfg.desc == "" || cfg.message == "" {
+		return nil, nil, errors.New("-desc and -message are required for protobuf input")
+	}
+
+	f, err := os.Open(cfg.desc)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	set, err := struct2csv.ReadDescriptorSet(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := addWellKnownTypes(set.Types); err != nil {
+		return nil, nil, err
+	}
+	mt, err := set.FindMessageType(cfg.message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("message %s: %w", cfg.message, err)
+	}
+
+	format := struct2csv.ProtoDelimited
+	if cfg.format == "protojson" {
+		format = struct2csv.ProtoJSON
+	}
+	msgs, err := struct2csv.NewProtoDecoder(in, mt, format, set.Types).DecodeAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return msgs, set, nil
+}
+
+// pathLabel returns the string field option of the set named by -path-label
+func pathLabel(set *struct2csv.DescriptorSet, name string) (protoreflect.ExtensionType, error) {
+	xt, err := set.Types.FindExtensionByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("path label %s: %w", name, err)
+	}
+	xd := xt.TypeDescriptor()
+	if xd.ContainingMessage().FullName() != "google.protobuf.FieldOptions" || xd.Kind() != protoreflect.StringKind || xd.IsList() {
+		return nil, fmt.Errorf("path label %s is not a string field option", name)
+	}
+	return xt, nil
+}
+
+// addWellKnownTypes registers the google.protobuf messages linked into the command,
+// Any payloads often hold them while the descriptor set does not import them
+func addWellKnownTypes(types *protoregistry.Types) error {
+	var err error
+	protoregistry.GlobalTypes.RangeMessages(func(mt protoreflect.MessageType) bool {
+		md := mt.Descriptor()
+		if md.ParentFile().Package() != "google.protobuf" {
+			return true
+		}
+		if _, findErr := types.FindMessageByName(md.FullName()); findErr == protoregistry.NotFound {
+			err = types.RegisterMessage(mt)
+		}
+		return err == nil
+	})
+	return err
+}
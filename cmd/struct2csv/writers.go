@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"struct2csv"
+)
+
+// writeResults writes results to -o, as a -bundle or in the -to format, and the
+// -mapping file
+func writeResults(cfg config, conv *struct2csv.StructConverter, results *struct2csv.KVs, autoInc *struct2csv.HeaderAutoIncrementConv) error {
+	format, err := mappingFormat(cfg.mappingFormat)
+	if err != nil {
+		return err
+	}
+
+	if cfg.bundle != "" {
+		bundleFormat := struct2csv.BundleZip
+		switch cfg.bundle {
+		case "zip":
+		case "tgz":
+			bundleFormat = struct2csv.BundleTarGz
+		default:
+			return fmt.Errorf("unknown bundle format %q", cfg.bundle)
+		}
+		return writeFile(cfg, cfg.output, func(w io.Writer) error {
+			return struct2csv.NewBundleWriter(w, struct2csv.WithBundleFormat(bundleFormat),
+				struct2csv.WithBundleMappingFormat(format), struct2csv.WithBundleConverter(conv)).WriteBundle(results)
+		})
+	}
+
+	if err := writeFile(cfg, cfg.output, func(w io.Writer) error {
+		return writeOutput(cfg, w, results)
+	}); err != nil {
+		return err
+	}
+
+	if cfg.mapping == "" {
+		return nil
+	}
+	return writeFile(cfg, cfg.mapping, func(w io.Writer) error {
+		if cfg.prevMapping != "" {
+			return autoInc.WriteMapping(w)
+		}
+		return struct2csv.WriteMappingAs(w, results, format)
+	})
+}
+
+// writeOutput writes results in the -to format
+func writeOutput(cfg config, w io.Writer, results *struct2csv.KVs) error {
+	switch cfg.to {
+	case "csv":
+		csvOpts, err := csvOptions(cfg)
+		if err != nil {
+			return err
+		}
+		return struct2csv.NewCSVWriter(w, csvOpts...).WriteCSV(results)
+	case "jsonl":
+		return struct2csv.NewJSONLWriter(w, struct2csv.WithJSONLOriginalKeys(cfg.header == "autoinc")).WriteJSONL(results)
+	case "xlsx":
+		xlsxOpts := []struct2csv.XLSXOption{struct2csv.WithXLSXTimeLayouts(time.RFC3339)}
+		if cfg.header == "autoinc" {
+			xlsxOpts = append(xlsxOpts, struct2csv.WithXLSXMappingSheet("mapping"))
+		}
+		return struct2csv.NewXLSXWriter(w, xlsxOpts...).WriteXLSX(results)
+	case "parquet":
+		return struct2csv.NewParquetWriter(w, struct2csv.WithParquetOriginalNames(cfg.header == "autoinc")).WriteParquet(results)
+	case "sql":
+		dialect, err := struct2csv.ParseSQLDialect(cfg.sqlDialect)
+		if err != nil {
+			return err
+		}
+		return struct2csv.NewSQLWriter(w, struct2csv.WithSQLDialect(dialect), struct2csv.WithSQLTable(cfg.sqlTable)).WriteSQL(results)
+	case "markdown":
+		return struct2csv.NewMarkdownWriter(w, tableOptions(cfg)...).WriteMarkdown(results)
+	case "html":
+		return struct2csv.NewHTMLWriter(w, tableOptions(cfg)...).WriteHTML(results)
+	case "fixed":
+		fixedOpts, err := fixedWidthOptions(cfg)
+		if err != nil {
+			return err
+		}
+		return struct2csv.NewFixedWidthWriter(w, fixedOpts...).WriteFixedWidth(results)
+	case "ltsv":
+		return struct2csv.NewLTSVWriter(w).WriteLTSV(results)
+	case "table":
+		return struct2csv.NewTextTableWriter(w, tableOptions(cfg)...).WriteTable(results)
+	case "vertical":
+		return struct2csv.NewTextTableWriter(w, tableOptions(cfg)...).WriteVertical(results)
+	default:
+		return fmt.Errorf("unknown output format %q", cfg.to)
+	}
+}
+
+func tableOptions(cfg config) []struct2csv.TableOption {
+	return []struct2csv.TableOption{
+		struct2csv.WithTableMaxWidth(cfg.maxWidth),
+		struct2csv.WithTableOriginalHeader(cfg.header == "autoinc"),
+		struct2csv.WithTableGroupHeader(cfg.groupHeader),
+	}
+}
+
+func fixedWidthOptions(cfg config) ([]struct2csv.FixedWidthOption, error) {
+	widths := make(map[string]int, len(cfg.widths))
+	for _, s := range cfg.widths {
+		i := strings.LastIndexByte(s, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("-width %q is not path=width", s)
+		}
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("-width %q is not path=width", s)
+		}
+		widths[s[:i]] = n
+	}
+	return []struct2csv.FixedWidthOption{
+		struct2csv.WithFixedWidths(widths),
+		struct2csv.WithFixedWidthHeader(!cfg.noHeader),
+		struct2csv.WithFixedWidthCRLF(cfg.crlf),
+	}, nil
+}
+
+func csvOptions(cfg config) ([]struct2csv.CSVOption, error) {
+	delimiter := []rune(cfg.delimiter)
+	if cfg.delimiter == "tab" {
+		delimiter = []rune{'\t'}
+	}
+	if len(delimiter) != 1 {
+		return nil, fmt.Errorf("delimiter %q is not a single character", cfg.delimiter)
+	}
+
+	return []struct2csv.CSVOption{
+		struct2csv.WithDelimiter(delimiter[0]),
+		struct2csv.WithCRLF(cfg.crlf),
+		struct2csv.WithAlwaysQuote(cfg.alwaysQuote),
+		struct2csv.WithBOM(cfg.bom),
+		struct2csv.WithHeader(!cfg.noHeader),
+		struct2csv.WithHeaderLevels(cfg.groupHeader),
+		struct2csv.WithFormulaEscape(cfg.formulaEscape),
+		struct2csv.WithNumericColumns(cfg.numericColumns...),
+	}, nil
+}
+
+func writeFile(cfg config, name string, write func(w io.Writer) error) error {
+	if name == "-" {
+		return write(cfg.stdout)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,175 @@
+package struct2csv
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ValueType is the kind of the values a column holds
+type ValueType string
+
+const (
+	ValueTypeNone   ValueType = ""       // the column never held a value
+	ValueTypeInt    ValueType = "int"    // int64 and smaller signed integers
+	ValueTypeUint   ValueType = "uint"   // uint64 and smaller unsigned integers
+	ValueTypeFloat  ValueType = "float"  // float64 and float32
+	ValueTypeNumber ValueType = "number" // json.Number, exact decimal text
+	ValueTypeBool   ValueType = "bool"   // bool
+	ValueTypeTime   ValueType = "time"   // time.Time
+	ValueTypeString ValueType = "string" // string, or values of different kinds
+)
+
+// ColumnInfo describes one column of KVs
+type ColumnInfo struct {
+	Path     string    `json:"path"`
+	Code     string    `json:"code"`      // the encoded header
+	Type     ValueType `json:"type"`      // the kind of the values, ValueTypeNone if the column is always empty
+	FirstRow int       `json:"first_row"` // index of the first converted row holding a value, -1 if none
+	Filled   int       `json:"filled"`    // number of rows holding a value
+}
+
+type columnStats struct {
+	valueType  ValueType
+	firstRow   int
+	filled     int
+	nonInteger bool // a value is a float, or a number not fitting int64
+}
+
+// Columns returns the columns in the order of GetSortMappingValues, which is the CSV column order
+func (kvs *KVs) Columns() []ColumnInfo {
+	paths := make(map[string]string, len(kvs.mapping))
+	for path, key := range kvs.mapping {
+		paths[key.String()] = path
+	}
+
+	keys := kvs.GetSortMappingValues()
+	columns := make([]ColumnInfo, 0, len(keys))
+	for _, key := range keys {
+		path := paths[key.String()]
+		column := ColumnInfo{Path: path, Code: key.String(), FirstRow: -1}
+		if st, ok := kvs.stats[path]; ok {
+			column.Type = st.valueType
+			column.FirstRow = st.firstRow
+			column.Filled = st.filled
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// Rows returns the number of rows holding at least one value
+func (kvs *KVs) Rows() int {
+	n := 0
+	for _, kv := range kvs.kvs {
+		if kv.Len() > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// observe records a value set in column path of the row being converted
+func (kvs *KVs) observe(path string, v interface{}) {
+	st, ok := kvs.stats[path]
+	if !ok {
+		st = &columnStats{firstRow: kvs.rows}
+		kvs.stats[path] = st
+	}
+	st.filled++
+	st.valueType = mergeValueType(st.valueType, valueTypeOf(v))
+	if !st.nonInteger && !fitsInt64(v) {
+		st.nonInteger = true
+	}
+	if st.valueType == ValueTypeInt && st.nonInteger {
+		// ints and a uint beyond int64, e.g. math.MaxUint64, neither fit int64 nor uint64
+		st.valueType = ValueTypeNumber
+	}
+}
+
+// integerColumn reports whether every value of the numeric column path fits int64,
+// e.g. a json.Number column holding IDs
+func (kvs *KVs) integerColumn(path string) bool {
+	st, ok := kvs.stats[path]
+	return ok && isNumericType(st.valueType) && !st.nonInteger
+}
+
+// fitsInt64 reports whether v is an integer of int64 range, values which are not numbers do
+func fitsInt64(v interface{}) bool {
+	switch v := v.(type) {
+	case float32, float64:
+		return false
+	case uint:
+		return uint64(v) <= math.MaxInt64
+	case uint64:
+		return v <= math.MaxInt64
+	case json.Number:
+		_, err := strconv.ParseInt(string(v), 10, 64)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+func valueTypeOf(v interface{}) ValueType {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, protoreflect.EnumNumber:
+		return ValueTypeInt
+	case uint, uint8, uint16, uint32, uint64:
+		return ValueTypeUint
+	case float32, float64:
+		return ValueTypeFloat
+	case json.Number:
+		return ValueTypeNumber
+	case bool:
+		return ValueTypeBool
+	case time.Time:
+		return ValueTypeTime
+	default:
+		return ValueTypeString
+	}
+}
+
+// mergeValueType returns the type that can hold values of both a and b
+func mergeValueType(a, b ValueType) ValueType {
+	switch {
+	case a == b || b == ValueTypeNone:
+		return a
+	case a == ValueTypeNone:
+		return b
+	case !isNumericType(a) || !isNumericType(b):
+		return ValueTypeString
+	case a == ValueTypeNumber || b == ValueTypeNumber:
+		return ValueTypeNumber
+	case a == ValueTypeFloat || b == ValueTypeFloat:
+		return ValueTypeFloat
+	default: // int and uint, observe makes it number if a uint does not fit int64
+		return ValueTypeInt
+	}
+}
+
+func isNumericType(t ValueType) bool {
+	return t == ValueTypeInt || t == ValueTypeUint || t == ValueTypeFloat || t == ValueTypeNumber
+}
+
+// rangeRows calls fn with the values of every row holding data in the order of keys,
+// missing values are nil. like CSVWriter.WriteCSV it consumes the values
+func (kvs *KVs) rangeRows(keys []KeyType, fn func(values []interface{}) error) error {
+	values := make([]interface{}, len(keys))
+	for _, kv := range kvs.kvs {
+		if kv.Len() == 0 { // Kv might have no data because it allocated memory ahead of time
+			continue
+		}
+
+		for i, key := range keys {
+			values[i], _ = kv.Get(key)
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
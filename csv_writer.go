@@ -1,22 +1,139 @@
 package struct2csv
 
 import (
+	"bufio"
 	"encoding/csv"
+	"errors"
 	"io"
 	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 	"unsafe"
 )
 
+const utf8BOM = "\xEF\xBB\xBF"
+
+type csvOptions struct {
+	comma       rune // field delimiter
+	useCRLF     bool // end lines with \r\n
+	alwaysQuote bool // quote every field, not only the ones that need it
+	bom         bool // start the output with a UTF-8 byte order mark
+	header      bool // WriteCSV writes the header row
+	autoFlush   bool // WriteCSV and WriteMapping flush when done
+	levels      bool // the header has one row per path level
+
+	formulaPrefix  string        // prepended to string values a spreadsheet would run as formula, empty disables it
+	numericColumns []pathPattern // columns whose numeric strings like "-1" are kept as they are
+}
+
+type CSVOption func(opts *csvOptions)
+
+// WithDelimiter sets the field delimiter, e.g. '\t' for TSV or ';' for Excel in EU locales
+func WithDelimiter(p rune) CSVOption {
+	return func(opts *csvOptions) {
+		opts.comma = p
+	}
+}
+
+// WithCRLF ends lines with \r\n instead of \n
+func WithCRLF(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.useCRLF = p
+	}
+}
+
+// WithAlwaysQuote quotes every field, by default only fields that need it are quoted
+func WithAlwaysQuote(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.alwaysQuote = p
+	}
+}
+
+// WithBOM starts the output with a UTF-8 byte order mark, which Excel needs to detect UTF-8
+func WithBOM(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.bom = p
+	}
+}
+
+// WithHeader sets whether WriteCSV writes the header row, the default is true.
+// WriteHeader writes it on its own
+func WithHeader(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.header = p
+	}
+}
+
+// WithAutoFlush sets whether WriteCSV and WriteMapping flush when done, the default is true.
+// without it several KVs can be written one after another, the caller must call Flush at the end
+func WithAutoFlush(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.autoFlush = p
+	}
+}
+
+// WithHeaderLevels writes the header as one row per path level instead of one row of
+// encoded headers, e.g. "B2", "0" and "B21" for "/B2/0/B21". a parent shared by
+// neighbouring columns is written once, the cells it spans are blank. a blank row ends
+// the header, so ReadHeaderLevels reads it back without knowing the depth of the paths
+func WithHeaderLevels(p bool) CSVOption {
+	return func(opts *csvOptions) {
+		opts.levels = p
+	}
+}
+
+// WithFormulaEscape protects spreadsheet users from CSV injection as OWASP recommends:
+// string values and header cells starting with '=', '+', '-', '@', tab or carriage return get prefix
+// prepended, usually "'" or "\t". numbers converted from numeric fields are never
+// changed, see WithNumericColumns for numbers held in strings
+func WithFormulaEscape(prefix string) CSVOption {
+	return func(opts *csvOptions) {
+		opts.formulaPrefix = prefix
+	}
+}
+
+// WithNumericColumns lists path patterns, in the syntax of WithInclude, of columns
+// whose string values may be numbers, e.g. "-12.5" from a decimal kept as string.
+// values of those columns which parse as a number are not escaped by WithFormulaEscape,
+// malformed patterns match nothing
+func WithNumericColumns(patterns ...string) CSVOption {
+	return func(opts *csvOptions) {
+		for _, pattern := range patterns {
+			opts.numericColumns = append(opts.numericColumns, splitPath(pattern))
+		}
+	}
+}
+
 // CSVWriter writes CSV data.
 type CSVWriter struct {
 	*csv.Writer
+	bw          *bufio.Writer // shared with csv.Writer, so records written by hand keep their order
+	opts        *csvOptions
+	started     bool // anything was written, the BOM is only written once
 	recordCache []string
 }
 
 // NewCSVWriter returns new CSVWriter
-func NewCSVWriter(w io.Writer) *CSVWriter {
+func NewCSVWriter(w io.Writer, options ...CSVOption) *CSVWriter {
+	opts := &csvOptions{
+		comma:     ',',
+		header:    true,
+		autoFlush: true,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := csv.NewWriter(bw) // reuses bw as its buffer
+	cw.Comma = opts.comma
+	cw.UseCRLF = opts.useCRLF
+
 	return &CSVWriter{
-		Writer:      csv.NewWriter(w),
+		Writer:      cw,
+		bw:          bw,
+		opts:        opts,
 		recordCache: make([]string, 0, 16000),
 	}
 }
@@ -30,43 +147,228 @@ func (w *CSVWriter) WriteMapping(kvs *KVs) error {
 		values = append(values, mapping[h].String())
 	}
 
-	return w.WriteAll([][]string{header, values})
+	for _, record := range [][]string{header, values} {
+		if err := w.writeRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return w.done()
 }
 
-// WriteCSV writes CSV data.
-func (w *CSVWriter) WriteCSV(results *KVs) error {
-	header := results.GetEncodedSortHeader()
+// WriteHeader writes the header row of results, or the rows of WithHeaderLevels.
+// the header cells are escaped like string values with WithFormulaEscape
+func (w *CSVWriter) WriteHeader(results *KVs) error {
+	if !w.opts.levels {
+		return w.writeRecord(w.escapeHeader(results.GetEncodedSortHeader()))
+	}
 
-	if err := w.Write(header); err != nil {
+	columns := results.Columns()
+	paths := make([]string, 0, len(columns))
+	for _, c := range columns {
+		paths = append(paths, c.Path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, record := range headerLevelRows(paths) {
+		if err := w.writeRecord(w.escapeHeader(record)); err != nil {
+			return err
+		}
+	}
+	return w.writeBlankRecord(len(paths))
+}
+
+// writeBlankRecord writes a record of width blank fields, which ends the rows of WithHeaderLevels.
+// a single blank field is quoted, csv.Reader skips empty lines
+func (w *CSVWriter) writeBlankRecord(width int) error {
+	if width > 1 || w.opts.alwaysQuote {
+		return w.writeRecord(make([]string, width))
+	}
+
+	w.begin()
+	w.bw.WriteString(`""`)
+	if w.opts.useCRLF {
+		_, err := w.bw.WriteString("\r\n")
 		return err
 	}
+	return w.bw.WriteByte('\n')
+}
+
+// WriteCSV writes CSV data.
+func (w *CSVWriter) WriteCSV(results *KVs) error {
+	if w.opts.header {
+		if err := w.WriteHeader(results); err != nil {
+			return err
+		}
+	}
 
 	// the oriHeader is equal to header but the type
 	// why not use header direct? because header is just a string
 	// the oriHeader is the type compatibility with results.kvs
 	oriHeader := results.GetSortMappingValues()
+	numeric := w.numericColumns(results, oriHeader)
 	for _, result := range results.kvs {
 		if result.Len() > 0 { // Kv might have no data because it allocated memory ahead of time
-			record := w.toRecord(result, oriHeader)
-			if err := w.Write(record); err != nil {
+			record := w.toRecord(result, oriHeader, numeric)
+			if err := w.writeRecord(record); err != nil {
 				return err
 			}
 			w.reset()
 		}
 	}
-	w.Flush()
 
+	return w.done()
+}
+
+// begin writes the BOM ahead of the first output
+func (w *CSVWriter) begin() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if w.opts.bom {
+		w.bw.WriteString(utf8BOM)
+	}
+}
+
+// done flushes if autoFlush is on and returns the first write error
+func (w *CSVWriter) done() error {
+	if w.opts.autoFlush {
+		w.Flush()
+	}
 	return w.Error()
 }
 
+func (w *CSVWriter) writeRecord(record []string) error {
+	w.begin()
+	if !w.opts.alwaysQuote {
+		return w.Write(record)
+	}
+	return w.writeQuoted(record)
+}
+
+// writeQuoted writes record like csv.Writer.Write, quoting every field
+func (w *CSVWriter) writeQuoted(record []string) error {
+	if !validDelim(w.opts.comma) {
+		return errInvalidDelim
+	}
+
+	for i, field := range record {
+		if i > 0 {
+			w.bw.WriteRune(w.opts.comma)
+		}
+		w.bw.WriteByte('"')
+		for len(field) > 0 {
+			j := strings.IndexAny(field, "\"\r\n")
+			if j < 0 {
+				j = len(field)
+			}
+			w.bw.WriteString(field[:j])
+			field = field[j:]
+			if len(field) == 0 {
+				break
+			}
+			switch field[0] {
+			case '"':
+				w.bw.WriteString(`""`)
+			case '\r':
+				if !w.opts.useCRLF {
+					w.bw.WriteByte('\r')
+				}
+			case '\n':
+				if w.opts.useCRLF {
+					w.bw.WriteString("\r\n")
+				} else {
+					w.bw.WriteByte('\n')
+				}
+			}
+			field = field[1:]
+		}
+		w.bw.WriteByte('"')
+	}
+	if w.opts.useCRLF {
+		_, err := w.bw.WriteString("\r\n")
+		return err
+	}
+	return w.bw.WriteByte('\n')
+}
+
+// errInvalidDelim is the error of csv.Writer for an invalid Comma
+var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
+
+// validDelim reports whether csv.Writer takes r as Comma
+func validDelim(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
 func (w *CSVWriter) reset() {
 	h := (*reflect.SliceHeader)(unsafe.Pointer(&w.recordCache))
 	h.Len = 0
 }
 
-func (w *CSVWriter) toRecord(kv *KeyValue, header []KeyType) []string {
-	for _, key := range header {
+// numericColumns marks the columns of header matching WithNumericColumns, nil if there are none
+func (w *CSVWriter) numericColumns(results *KVs, header []KeyType) []bool {
+	if w.opts.formulaPrefix == "" || len(w.opts.numericColumns) == 0 {
+		return nil
+	}
+
+	paths := make(map[string]string, len(results.mapping))
+	for path, key := range results.mapping {
+		paths[key.String()] = path
+	}
+
+	numeric := make([]bool, len(header))
+	for i, key := range header {
+		segs := splitPath(paths[key.String()])
+		for _, p := range w.opts.numericColumns {
+			if p.match(segs, false) {
+				numeric[i] = true
+				break
+			}
+		}
+	}
+	return numeric
+}
+
+// escapeHeader returns header with every cell passed through escapeFormula, header is not changed
+func (w *CSVWriter) escapeHeader(header []string) []string {
+	if w.opts.formulaPrefix == "" {
+		return header
+	}
+
+	escaped := make([]string, 0, len(header))
+	for _, s := range header {
+		escaped = append(escaped, w.escapeFormula(s, false))
+	}
+	return escaped
+}
+
+// escapeFormula prefixes s if a spreadsheet would evaluate it
+func (w *CSVWriter) escapeFormula(s string, numeric bool) string {
+	if s == "" {
+		return s
+	}
+
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if numeric {
+			if _, err := strconv.ParseFloat(s, 64); err == nil {
+				return s
+			}
+		}
+		return w.opts.formulaPrefix + s
+	}
+	return s
+}
+
+func (w *CSVWriter) toRecord(kv *KeyValue, header []KeyType, numeric []bool) []string {
+	for i, key := range header {
 		if value, ok := kv.Get(key); ok {
+			if str, isString := value.(string); isString && w.opts.formulaPrefix != "" {
+				w.recordCache = append(w.recordCache, w.escapeFormula(str, numeric != nil && numeric[i]))
+				continue
+			}
 			w.recordCache = append(w.recordCache, toString(value))
 		} else {
 			w.recordCache = append(w.recordCache, "")
@@ -0,0 +1,236 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter_WriteCSV(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+	}
+	data := []row{{Name: `say "hi"`, Count: 1}, {Name: "a;b"}}
+
+	tests := []struct {
+		name string
+		opts []CSVOption
+		want string
+	}{
+		{
+			name: "default",
+			want: "/Count,/Name\n1,\"say \"\"hi\"\"\"\n,a;b\n",
+		},
+		{
+			name: "semicolon",
+			opts: []CSVOption{WithDelimiter(';'), WithCRLF(true)},
+			want: "/Count;/Name\r\n1;\"say \"\"hi\"\"\"\r\n;\"a;b\"\r\n",
+		},
+		{
+			name: "always quote with bom",
+			opts: []CSVOption{WithDelimiter('\t'), WithAlwaysQuote(true), WithBOM(true)},
+			want: "\xEF\xBB\xBF\"/Count\"\t\"/Name\"\n\"1\"\t\"say \"\"hi\"\"\"\n\"\"\t\"a;b\"\n",
+		},
+		{
+			name: "no header",
+			opts: []CSVOption{WithHeader(false)},
+			want: "1,\"say \"\"hi\"\"\"\n,a;b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewCSVWriter(&b, tt.opts...).WriteCSV(kvs); err != nil {
+				t.Fatalf("WriteCSV() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteCSV() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriter_WriteCSVAlwaysQuote(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+	}
+	data := []row{{Name: "a\nb", Count: 1}, {Name: "c\r\nd"}}
+
+	tests := []struct {
+		name    string
+		opts    []CSVOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "lf",
+			opts: []CSVOption{WithAlwaysQuote(true)},
+			want: "\"/Count\",\"/Name\"\n\"1\",\"a\nb\"\n\"\",\"c\r\nd\"\n",
+		},
+		{
+			name: "crlf",
+			opts: []CSVOption{WithAlwaysQuote(true), WithCRLF(true)},
+			want: "\"/Count\",\"/Name\"\r\n\"1\",\"a\r\nb\"\r\n\"\",\"c\r\nd\"\r\n",
+		},
+		{
+			name: "crlf without always quote",
+			opts: []CSVOption{WithCRLF(true)},
+			want: "/Count,/Name\r\n1,\"a\r\nb\"\r\n,\"c\r\nd\"\r\n",
+		},
+		{
+			name:    "invalid delimiter",
+			opts:    []CSVOption{WithAlwaysQuote(true), WithDelimiter('"')},
+			wantErr: true,
+		},
+		{
+			name:    "invalid delimiter without always quote",
+			opts:    []CSVOption{WithDelimiter('"')},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			err = NewCSVWriter(&b, tt.opts...).WriteCSV(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && b.String() != tt.want {
+				t.Errorf("WriteCSV() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriter_WriteCSVTime(t *testing.T) {
+	type row struct {
+		At    time.Time
+		Until *time.Time
+	}
+	until := time.Date(2024, 1, 2, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	data := []row{
+		{At: time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC), Until: &until},
+		{At: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewCSVWriter(&b).WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "/At,/Until\n" +
+		"2024-01-02T03:04:05.5Z,2024-01-02T12:00:00+09:00\n" +
+		"2024-01-03T00:00:00Z,\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCSVWriter_Concatenate(t *testing.T) {
+	var b strings.Builder
+	w := NewCSVWriter(&b, WithHeader(false), WithAutoFlush(false))
+
+	for i, chunk := range []string{`[{"a": 1, "b": 2}]`, `[{"a": 3, "b": 4}]`} {
+		conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+		kvs, err := conv.ConvertJSON(strings.NewReader(chunk))
+		if err != nil {
+			t.Fatalf("ConvertJSON() error = %v", err)
+		}
+		if i == 0 {
+			if err := w.WriteHeader(kvs); err != nil {
+				t.Fatalf("WriteHeader() error = %v", err)
+			}
+		}
+		if err := w.WriteCSV(kvs); err != nil {
+			t.Fatalf("WriteCSV() error = %v", err)
+		}
+		if b.Len() != 0 {
+			t.Fatalf("WriteCSV() flushed %q", b.String())
+		}
+	}
+
+	w.Flush()
+	if want := "/a,/b\n1,2\n3,4\n"; b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCSVWriter_WriteCSVFormulaEscape(t *testing.T) {
+	type row struct {
+		Amount  string
+		Balance int
+		Comment string
+	}
+	data := []row{
+		{Amount: "-12.5", Balance: -3, Comment: "=HYPERLINK(\"http://x\")"},
+		{Amount: "-1+1", Comment: "@SUM(A1)"},
+	}
+
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewCSVWriter(&b, WithFormulaEscape("'"), WithNumericColumns("/Amount")).WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "/Amount,/Balance,/Comment\n-12.5,-3,\"'=HYPERLINK(\"\"http://x\"\")\"\n'-1+1,,'@SUM(A1)\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCSVWriter_WriteHeaderFormulaEscape(t *testing.T) {
+	labels, _ := NewHeaderDictionaryConv(map[string]string{"/=HYPERLINK(1)/@x": "=HYPERLINK(2)"}, nil)
+
+	tests := []struct {
+		name       string
+		headerConv HeaderConverter
+		opts       []CSVOption
+		want       string
+	}{
+		{name: "flat", headerConv: labels, want: "'=HYPERLINK(2)\n1\n"},
+		{name: "levels", headerConv: NewHeaderOriginalStringConv(), opts: []CSVOption{WithHeaderLevels(true)}, want: "'=HYPERLINK(1)\n'@x\n\"\"\n1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(tt.headerConv, WithIsObjArray(false))
+			kvs, err := conv.Convert(map[string]map[string]int{"=HYPERLINK(1)": {"@x": 1}})
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewCSVWriter(&b, append(tt.opts, WithFormulaEscape("'"))...).WriteCSV(kvs); err != nil {
+				t.Fatalf("WriteCSV() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteCSV() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
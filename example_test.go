@@ -1,13 +1,11 @@
 package struct2csv
 
 import (
-	"archive/zip"
 	"log"
 	"os"
-	"time"
 )
 
-func ExampleStruct2CSVFile() {
+func Example_struct2CSVFile() {
 	data := []testStruct{{
 		A1: 1,
 		A2: true,
@@ -41,28 +39,11 @@ func ExampleStruct2CSVFile() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer outFile.Close()
 
-	zipW := zip.NewWriter(outFile)
-	defer zipW.Close()
-
-	f, _ := zipW.CreateHeader(&zip.FileHeader{
-		Name:     "test.csv",
-		Modified: time.Now(),
-		Method:   zip.Deflate,
-	})
-
-	csvWriter := NewCSVWriter(f)
-	if err := csvWriter.WriteCSV(result); err != nil {
-		log.Fatal(err)
-	}
-
-	f2, _ := zipW.CreateHeader(&zip.FileHeader{
-		Name:     "mapping.csv",
-		Modified: time.Now(),
-		Method:   zip.Deflate,
-	})
-
-	if err := NewCSVWriter(f2).WriteMapping(result); err != nil {
+	// test.csv, mapping.csv and manifest.json
+	bundle := NewBundleWriter(outFile, WithBundleNames("test.csv", "mapping.csv", ""), WithBundleConverter(conv))
+	if err := bundle.WriteBundle(result); err != nil {
 		log.Fatal(err)
 	}
 
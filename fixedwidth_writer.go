@@ -0,0 +1,275 @@
+package struct2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Alignment is where a value sits in a fixed width field
+type Alignment int
+
+const (
+	AlignAuto  Alignment = iota // numeric columns right, others left
+	AlignLeft                   // padded on the right
+	AlignRight                  // padded on the left
+)
+
+// Overflow is what FixedWidthWriter does with a value longer than its field
+type Overflow int
+
+const (
+	OverflowTruncate Overflow = iota // cut the value to the field, keeping its start
+	OverflowError                    // stop writing and return an error
+)
+
+type fixedWidthColumn struct {
+	width int // 0 takes the width of the longest value
+	align Alignment
+	set   bool // align was given for the column
+}
+
+type fixedWidthOptions struct {
+	columns  map[string]fixedWidthColumn // by path
+	align    Alignment
+	overflow Overflow
+	header   bool
+	useCRLF  bool
+}
+
+// FixedWidthOption configures FixedWidthWriter
+type FixedWidthOption func(opts *fixedWidthOptions)
+
+// WithFixedWidthColumn sets the width of the column at path, e.g. "/B2/0/B21", and its
+// alignment. a width of 0 keeps the width of WithFixedWidths or computed from the data
+func WithFixedWidthColumn(path string, width int, align Alignment) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		c := opts.columns[path]
+		if width > 0 {
+			c.width = width
+		}
+		c.align, c.set = align, true
+		opts.columns[path] = c
+	}
+}
+
+// WithFixedWidths sets the widths of the columns by path, the other columns are computed from the data
+func WithFixedWidths(widths map[string]int) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		for path, width := range widths {
+			c := opts.columns[path]
+			c.width = width
+			opts.columns[path] = c
+		}
+	}
+}
+
+// WithFixedWidthAlignment sets the alignment of the columns without one of their own. default AlignAuto
+func WithFixedWidthAlignment(align Alignment) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		opts.align = align
+	}
+}
+
+// WithFixedWidthOverflow sets what to do with values longer than their field. default OverflowTruncate.
+// header cells follow it too, computed widths fit them but a supplied width may not
+func WithFixedWidthOverflow(overflow Overflow) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		opts.overflow = overflow
+	}
+}
+
+// WithFixedWidthHeader writes a header record of the encoded headers, escaped like the values.
+// computed widths fit them
+func WithFixedWidthHeader(p bool) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		opts.header = p
+	}
+}
+
+// WithFixedWidthCRLF ends the records with \r\n
+func WithFixedWidthCRLF(p bool) FixedWidthOption {
+	return func(opts *fixedWidthOptions) {
+		opts.useCRLF = p
+	}
+}
+
+// FixedWidthField is the place of a column in the records of FixedWidthWriter,
+// offsets and widths are in characters
+type FixedWidthField struct {
+	Path   string
+	Code   string
+	Offset int
+	Width  int
+}
+
+// FixedWidthWriter writes KVs as fixed width records, every value padded with spaces
+// to the width of its column. widths are counted in characters, not bytes.
+// line breaks and tabs in values are written as \n, \r and \t to keep a record on one line,
+// other control characters as \x1b or \u009b and backslashes as \\.
+// when every column has a width the rows are streamed, otherwise they are held in
+// memory to compute the missing widths
+type FixedWidthWriter struct {
+	w      *bufio.Writer
+	opts   *fixedWidthOptions
+	fields []FixedWidthField
+}
+
+// NewFixedWidthWriter returns new FixedWidthWriter
+func NewFixedWidthWriter(w io.Writer, options ...FixedWidthOption) *FixedWidthWriter {
+	opts := &fixedWidthOptions{columns: map[string]fixedWidthColumn{}}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &FixedWidthWriter{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// Fields returns the layout of the records written by the last WriteFixedWidth
+func (w *FixedWidthWriter) Fields() []FixedWidthField {
+	return w.fields
+}
+
+// WriteFixedWidth writes the rows of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *FixedWidthWriter) WriteFixedWidth(results *KVs) error {
+	columns := results.Columns()
+	widths := make([]int, len(columns))
+	right := make([]bool, len(columns))
+	computed := false
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = escapeRecord(c.Code)
+		column := w.opts.columns[c.Path]
+		widths[i] = column.width
+		if widths[i] <= 0 {
+			computed = true
+			if w.opts.header {
+				widths[i] = utf8.RuneCountInString(header[i])
+			}
+		}
+		align := w.opts.align
+		if column.set {
+			align = column.align
+		}
+		right[i] = align == AlignRight || align == AlignAuto && isNumericType(c.Type)
+	}
+
+	var rows [][]string
+	keys := results.GetSortMappingValues()
+	if computed {
+		err := results.rangeRows(keys, func(values []interface{}) error {
+			row := make([]string, len(values))
+			for i, v := range values {
+				row[i] = escapeRecord(displayString(v))
+				if w.opts.columns[columns[i].Path].width > 0 {
+					continue
+				}
+				if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+					widths[i] = n
+				}
+			}
+			rows = append(rows, row)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.fields = make([]FixedWidthField, 0, len(columns))
+	offset := 0
+	for i, c := range columns {
+		w.fields = append(w.fields, FixedWidthField{Path: c.Path, Code: c.Code, Offset: offset, Width: widths[i]})
+		offset += widths[i]
+	}
+
+	var b strings.Builder
+	if w.opts.header {
+		for i, c := range columns {
+			if err := w.appendField(&b, header[i], widths[i], false); err != nil {
+				return fmt.Errorf("WriteFixedWidth: header of %s: %w", c.Path, err)
+			}
+		}
+		w.endRecord(&b)
+	}
+
+	record := 0
+	writeRow := func(row []string) error {
+		record++
+		for i, s := range row {
+			if err := w.appendField(&b, s, widths[i], right[i]); err != nil {
+				return fmt.Errorf("WriteFixedWidth: record %d %s: %w", record, columns[i].Path, err)
+			}
+		}
+		w.endRecord(&b)
+		return nil
+	}
+
+	if computed {
+		for _, row := range rows {
+			if err := writeRow(row); err != nil {
+				return err
+			}
+		}
+	} else {
+		row := make([]string, len(columns))
+		err := results.rangeRows(keys, func(values []interface{}) error {
+			for i, v := range values {
+				row[i] = escapeRecord(displayString(v))
+			}
+			return writeRow(row)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.w.Flush()
+}
+
+func (w *FixedWidthWriter) appendField(b *strings.Builder, s string, width int, right bool) error {
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		if w.opts.overflow == OverflowError {
+			return fmt.Errorf("%q is longer than %d", s, width)
+		}
+		s = truncateRunes(s, width)
+		n = width
+	}
+
+	pad := strings.Repeat(" ", width-n)
+	if right {
+		b.WriteString(pad)
+		b.WriteString(s)
+	} else {
+		b.WriteString(s)
+		b.WriteString(pad)
+	}
+	return nil
+}
+
+func (w *FixedWidthWriter) endRecord(b *strings.Builder) {
+	w.w.WriteString(b.String())
+	if w.opts.useCRLF {
+		w.w.WriteString("\r\n")
+	} else {
+		w.w.WriteByte('\n')
+	}
+	b.Reset()
+}
+
+// truncateRunes returns the first n runes of s
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
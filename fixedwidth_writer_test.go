@@ -0,0 +1,120 @@
+package struct2csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFixedWidthWriter_WriteFixedWidth(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+		Note  string
+	}
+	data := []row{{Name: "apple", Count: 12, Note: "a\tb"}, {Name: "日本", Count: 3}}
+
+	tests := []struct {
+		name       string
+		opts       []FixedWidthOption
+		want       string
+		wantFields []FixedWidthField
+		wantErr    bool
+	}{
+		{
+			name: "computed",
+			want: "12applea\\tb\n" +
+				" 3日本       \n",
+			wantFields: []FixedWidthField{
+				{Path: "/Count", Code: "/Count", Offset: 0, Width: 2},
+				{Path: "/Name", Code: "/Name", Offset: 2, Width: 5},
+				{Path: "/Note", Code: "/Note", Offset: 7, Width: 4},
+			},
+		},
+		{
+			name: "header and crlf",
+			opts: []FixedWidthOption{WithFixedWidthHeader(true), WithFixedWidthCRLF(true)},
+			want: "/Count/Name/Note\r\n" +
+				"    12applea\\tb \r\n" +
+				"     3日本        \r\n",
+		},
+		{
+			name: "supplied widths truncate",
+			opts: []FixedWidthOption{
+				WithFixedWidthColumn("/Name", 0, AlignRight),
+				WithFixedWidths(map[string]int{"/Count": 4, "/Name": 3, "/Note": 2}),
+			},
+			want: "  12appa\\\n" +
+				"   3 日本  \n",
+		},
+		{
+			name: "left aligned",
+			opts: []FixedWidthOption{WithFixedWidthAlignment(AlignLeft), WithFixedWidthColumn("/Count", 3, AlignAuto)},
+			want: " 12applea\\tb\n" +
+				"  3日本       \n",
+		},
+		{
+			name: "header truncated",
+			opts: []FixedWidthOption{WithFixedWidthHeader(true), WithFixedWidths(map[string]int{"/Count": 3, "/Name": 6, "/Note": 4})},
+			want: "/Co/Name /Not\n" +
+				" 12apple a\\tb\n" +
+				"  3日本        \n",
+		},
+		{
+			name: "header overflow error",
+			opts: []FixedWidthOption{
+				WithFixedWidthHeader(true),
+				WithFixedWidths(map[string]int{"/Count": 3, "/Name": 6, "/Note": 4}),
+				WithFixedWidthOverflow(OverflowError),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "overflow error",
+			opts:    []FixedWidthOption{WithFixedWidthColumn("/Name", 4, AlignLeft), WithFixedWidthOverflow(OverflowError)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			w := NewFixedWidthWriter(&b, tt.opts...)
+			err = w.WriteFixedWidth(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteFixedWidth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteFixedWidth() = \n%q\nwant\n%q", b.String(), tt.want)
+			}
+			if tt.wantFields != nil && !reflect.DeepEqual(w.Fields(), tt.wantFields) {
+				t.Errorf("Fields() = %v, want %v", w.Fields(), tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestFixedWidthWriter_WriteFixedWidthHeaderEscape(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert([]map[string]string{{"a\tb": "x"}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewFixedWidthWriter(&b, WithFixedWidthHeader(true)).WriteFixedWidth(kvs); err != nil {
+		t.Fatalf("WriteFixedWidth() error = %v", err)
+	}
+	if want := "/a\\tb\nx    \n"; b.String() != want {
+		t.Errorf("WriteFixedWidth() = %q, want %q", b.String(), want)
+	}
+}
@@ -1,13 +1,28 @@
 package struct2csv
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
+const (
+	anyFullName      protoreflect.FullName    = "google.protobuf.Any"
+	anyTypeURLNumber protoreflect.FieldNumber = 1
+	anyValueNumber   protoreflect.FieldNumber = 2
+	typeColumnName                            = "$type"
+)
+
+// time.Time is kept as one value instead of being flattened like other structs
+var timeType = reflect.TypeOf(time.Time{})
+
 // if index = -1, don't use cache map, caller must use append the result to slice
 func (s *StructConverter) doFlatten(obj interface{}, index int) (*KeyValue, error) {
 	var f *KeyValue
@@ -20,6 +35,7 @@ func (s *StructConverter) doFlatten(obj interface{}, index int) (*KeyValue, erro
 	if err := s.flatten(f, obj, key); err != nil {
 		return nil, err
 	}
+	s.kvs.rows++
 	return f, nil
 }
 
@@ -36,6 +52,17 @@ func (s *StructConverter) flatten(out *KeyValue, obj interface{}, key PathBuilde
 		return nil
 	}
 
+	switch value.Type() {
+	case jsonNumberType:
+		s.set(out, key.String(), json.Number(value.String()))
+		return nil
+	case timeType:
+		if value.CanInterface() {
+			s.set(out, key.String(), value.Interface().(time.Time))
+		}
+		return nil
+	}
+
 	switch value.Kind() {
 	case reflect.Map:
 		return s.flattenMap(out, value, key)
@@ -54,7 +81,7 @@ func (s *StructConverter) flatten(out *KeyValue, obj interface{}, key PathBuilde
 	case reflect.Bool:
 		s.set(out, key.String(), value.Bool())
 	case reflect.Ptr:
-		if value.Elem().Kind() == reflect.Struct {
+		if value.Elem().Kind() == reflect.Struct && value.Elem().Type() != timeType {
 			return s.flattenStruct(out, value, key)
 		} else {
 			return s.flatten(out, value.Elem(), key)
@@ -74,6 +101,9 @@ func (s *StructConverter) flattenMap(out *KeyValue, value reflect.Value, prefix
 
 		pointer := prefix.Clone(s.opts.strBuilderCap)
 		pointer.AppendString(k.String())
+		if !s.visit(pointer) {
+			continue
+		}
 		if err := s.flatten(out, vv, pointer); err != nil {
 			return err
 		}
@@ -91,6 +121,9 @@ func (s *StructConverter) flattenSlice(out *KeyValue, value reflect.Value, prefi
 
 		pointer := prefix.Clone(s.opts.strBuilderCap)
 		pointer.AppendString(strconv.Itoa(i))
+		if !s.visit(pointer) {
+			continue
+		}
 		if err := s.flatten(out, vv, pointer); err != nil {
 			return err
 		}
@@ -123,6 +156,16 @@ func (s *StructConverter) flattenStruct(out *KeyValue, value reflect.Value, pref
 
 			pointer := prefix.Clone(s.opts.strBuilderCap)
 			pointer.AppendString(f.Name)
+			if !s.visit(pointer) {
+				continue
+			}
+			if s.opts.typeColumn && f.Type.Kind() == reflect.Interface && f.Type.NumMethod() > 0 {
+				// only a field of a named interface is polymorphic, interface{} mostly holds
+				// decoded JSON, and the elements of a slice like []proto.Message are not fields
+				typePointer := pointer.Clone(s.opts.strBuilderCap)
+				typePointer.AppendString(typeColumnName)
+				s.set(out, typePointer.String(), typeName(vv.Elem()))
+			}
 			if err := s.flatten(out, vv, pointer); err != nil {
 				return err
 			}
@@ -137,6 +180,21 @@ func (s *StructConverter) flattenStruct(out *KeyValue, value reflect.Value, pref
 // resolve proto struct
 func (s *StructConverter) flattenProtoStruct(out *KeyValue, value protoreflect.Value, prefix PathBuilder) error {
 	msg := value.Message()
+	if s.opts.anyResolver != nil && msg.Descriptor().FullName() == anyFullName {
+		unpacked, err := s.unpackAny(msg)
+		if err != nil {
+			return err
+		}
+		if unpacked != nil {
+			if s.opts.anyTypeColumn {
+				pointer := prefix.Clone(s.opts.strBuilderCap)
+				pointer.AppendString(typeColumnName)
+				s.set(out, pointer.String(), string(unpacked.Descriptor().FullName()))
+			}
+			return s.flattenProtoStruct(out, protoreflect.ValueOfMessage(unpacked), prefix)
+		}
+	}
+
 	var err error
 	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
 		if !value.IsValid() {
@@ -144,12 +202,112 @@ func (s *StructConverter) flattenProtoStruct(out *KeyValue, value protoreflect.V
 		}
 
 		pointer := prefix.Clone(s.opts.strBuilderCap)
-		pointer.AppendString(fd.TextName())
+		pointer.AppendString(s.protoFieldName(fd))
+		if !s.visit(pointer) {
+			return true
+		}
 		err = s.flattenProto(out, fd, value, pointer)
 		return err == nil
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	if s.opts.typeColumn {
+		s.flattenProtoOneofs(out, msg, prefix)
+	}
+
+	if !s.opts.emitUnpopulated {
+		return nil
+	}
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if msg.Has(fd) {
+			continue
+		}
+
+		pointer := prefix.Clone(s.opts.strBuilderCap)
+		pointer.AppendString(s.protoFieldName(fd))
+		if !s.visit(pointer) {
+			continue
+		}
+		if err := s.flattenProtoUnpopulated(out, msg, fd, pointer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flattenProtoOneofs sets a "<oneof>/$type" column holding the name of the chosen case of every oneof in msg
+func (s *StructConverter) flattenProtoOneofs(out *KeyValue, msg protoreflect.Message, prefix PathBuilder) {
+	oneofs := msg.Descriptor().Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if od.IsSynthetic() { // proto3 optional
+			continue
+		}
+
+		fd := msg.WhichOneof(od)
+		if fd == nil && !s.opts.emitUnpopulated {
+			continue
+		}
+
+		pointer := prefix.Clone(s.opts.strBuilderCap)
+		if s.opts.protoNaming == ProtoFullName {
+			pointer.AppendString(string(od.FullName()))
+		} else {
+			pointer.AppendString(string(od.Name()))
+		}
+		pointer.AppendString(typeColumnName)
+		if fd == nil {
+			s.register(pointer.String())
+		} else {
+			s.set(out, pointer.String(), s.protoFieldName(fd))
+		}
+	}
+}
+
+// typeName returns the full name of a protobuf message,
+// or the package qualified Go type name of any other value with pointers dereferenced
+func typeName(value reflect.Value) string {
+	if value.CanInterface() {
+		if msg, ok := value.Interface().(proto.Message); ok {
+			return string(msg.ProtoReflect().Descriptor().FullName())
+		}
+	}
+
+	t := value.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// unpackAny returns the concrete message packed in a google.protobuf.Any,
+// or nil if the Any is empty or its type is unknown to the resolver
+func (s *StructConverter) unpackAny(msg protoreflect.Message) (protoreflect.Message, error) {
+	fields := msg.Descriptor().Fields()
+	typeURL := msg.Get(fields.ByNumber(anyTypeURLNumber)).String()
+	if typeURL == "" {
+		return nil, nil
+	}
+
+	mt, err := s.opts.anyResolver.FindMessageByURL(typeURL)
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("flattenProto: resolve Any %s: %w", typeURL, err)
+	}
+
+	payload := mt.New()
+	if err := proto.Unmarshal(msg.Get(fields.ByNumber(anyValueNumber)).Bytes(), payload.Interface()); err != nil {
+		return nil, fmt.Errorf("flattenProto: unpack Any %s: %w", typeURL, err)
+	}
+	return payload, nil
 }
 
 func (s *StructConverter) flattenProto(out *KeyValue, fd protoreflect.FieldDescriptor, value protoreflect.Value, key PathBuilder) error {
@@ -227,6 +385,9 @@ func (s *StructConverter) flattenProtoSlice(out *KeyValue, value protoreflect.Va
 
 		pointer := prefix.Clone(s.opts.strBuilderCap)
 		pointer.AppendString(strconv.Itoa(i))
+		if !s.visit(pointer) {
+			continue
+		}
 		if err := s.flattenProto(out, nil, elem, pointer); err != nil {
 			return err
 		}
@@ -244,6 +405,9 @@ func (s *StructConverter) flattenProtoMap(out *KeyValue, value protoreflect.Valu
 
 		pointer := prefix.Clone(s.opts.strBuilderCap)
 		pointer.AppendString(k.String())
+		if !s.visit(pointer) {
+			return true
+		}
 		err = s.flattenProto(out, nil, v, pointer)
 		return err == nil
 	})
@@ -252,11 +416,32 @@ func (s *StructConverter) flattenProtoMap(out *KeyValue, value protoreflect.Valu
 }
 
 func (s *StructConverter) set(out *KeyValue, k string, v interface{}) {
+	if kt, ok := s.register(k); ok {
+		out.Set(kt, v)
+		s.kvs.observe(k, v)
+	}
+}
+
+// register adds the column k to the mapping without setting a value,
+// it returns false if k is filtered out by WithInclude or WithExclude
+func (s *StructConverter) register(k string) (KeyType, bool) {
+	if s.filter != nil && !s.filter.keep(k) {
+		return nil, false
+	}
+
 	kt, ok := s.kvs.mapping[k]
 	if !ok {
 		kt = s.headerConv.ConvertHeader(k)
+		s.kvs.mapping[k] = kt
+		if s.kvs.insertionOrder {
+			s.kvs.order = append(s.kvs.order, k)
+		}
 	}
 
-	s.kvs.mapping[k] = kt
-	out.Set(kt, v)
+	return kt, true
+}
+
+// visit reports whether the subtree at key may produce columns
+func (s *StructConverter) visit(key PathBuilder) bool {
+	return s.filter == nil || s.filter.visit(key.path())
 }
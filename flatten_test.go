@@ -0,0 +1,382 @@
+package struct2csv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestStructConverter_ConvertAny(t *testing.T) {
+	payload, err := anypb.New(durationpb.New(3 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []*typepb.Option{{Name: "timeout", Value: payload}}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		paths []string
+	}{
+		{
+			name:  "packed",
+			paths: []string{"/name", "/value/type_url"},
+		},
+		{
+			name:  "unpacked",
+			opts:  []Option{WithAnyResolver(protoregistry.GlobalTypes)},
+			paths: []string{"/name", "/value/seconds"},
+		},
+		{
+			name:  "unpacked with type column",
+			opts:  []Option{WithAnyResolver(protoregistry.GlobalTypes), WithAnyTypeColumn(true)},
+			paths: []string{"/name", "/value/$type", "/value/seconds"},
+		},
+		{
+			name:  "unknown type",
+			opts:  []Option{WithAnyResolver(new(protoregistry.Types))},
+			paths: []string{"/name", "/value/type_url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), tt.opts...)
+			got, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			paths := got.GetUnEncodedSortHeader()
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Convert() got UnEncodedSortHeader = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestStructConverter_ConvertProtoNaming(t *testing.T) {
+	file := testEventFile(t)
+	column := dynamicpb.NewExtensionType(file.Extensions().ByName("column"))
+	event := newTestEvent(t, file, `{"id": "e1", "createdAt": "7"}`)
+
+	// the column of a descriptor set extends the set's own copy of FieldOptions
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(file)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := ReadDescriptorSet(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setColumn, err := set.Types.FindExtensionByName("struct2csv.test.column")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		paths []string
+	}{
+		{
+			name:  "text name",
+			paths: []string{"/created_at", "/id"},
+		},
+		{
+			name:  "json name",
+			opts:  []Option{WithProtoFieldNaming(ProtoJSONName)},
+			paths: []string{"/createdAt", "/id"},
+		},
+		{
+			name:  "full name",
+			opts:  []Option{WithProtoFieldNaming(ProtoFullName)},
+			paths: []string{"/struct2csv.test.Event.created_at", "/struct2csv.test.Event.id"},
+		},
+		{
+			name:  "field number",
+			opts:  []Option{WithProtoFieldNaming(ProtoFieldNumber)},
+			paths: []string{"/1", "/2"},
+		},
+		{
+			name:  "label option",
+			opts:  []Option{WithProtoFieldNaming(ProtoJSONName), WithProtoFieldLabel(column)},
+			paths: []string{"/Event ID", "/createdAt"},
+		},
+		{
+			name:  "label option of a descriptor set",
+			opts:  []Option{WithProtoFieldLabel(setColumn)},
+			paths: []string{"/Event ID", "/created_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), tt.opts...)
+			got, err := conv.Convert([]proto.Message{event})
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			paths := got.GetUnEncodedSortHeader()
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Convert() got UnEncodedSortHeader = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestStructConverter_SchemaForMessage(t *testing.T) {
+	file := testEventFile(t)
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+
+	got := conv.SchemaForMessage(file.Messages().ByName("Event"))
+	want := []string{"/id", "/created_at", "/retries", "/text", "/point/x", "/point/y", "/tags/*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaForMessage() = %v, want %v", got, want)
+	}
+}
+
+func TestStructConverter_ConvertEmitUnpopulated(t *testing.T) {
+	file := testEventFile(t)
+	data := []proto.Message{
+		newTestEvent(t, file, `{"id": "e1"}`),
+		newTestEvent(t, file, `{"id": "e2", "retries": 0}`),
+	}
+
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), WithEmitUnpopulated(true))
+	got, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	paths := got.GetUnEncodedSortHeader()
+	wantPaths := []string{"/created_at", "/id", "/point/x", "/point/y", "/retries", "/text"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("Convert() got UnEncodedSortHeader = %v, want %v", paths, wantPaths)
+	}
+
+	wantRows := [][]interface{}{
+		{int64(0), "e1", nil, nil, nil, nil},
+		{int64(0), "e2", nil, nil, int64(0), nil},
+	}
+	for i, want := range wantRows {
+		row := make([]interface{}, 0, len(paths))
+		for _, path := range paths {
+			v, _ := got.getKVElem(i).Get(got.GetMapping()[path])
+			row = append(row, v)
+		}
+		if !reflect.DeepEqual(row, want) {
+			t.Errorf("Convert() row %d = %v, want %v", i, row, want)
+		}
+	}
+}
+
+type testShape interface {
+	area() int
+}
+
+type testCircle struct {
+	R int
+}
+
+func (c testCircle) area() int { return 3 * c.R * c.R }
+
+type testSquare struct {
+	S int
+}
+
+func (s *testSquare) area() int { return s.S * s.S }
+
+func TestStructConverter_ConvertTypeColumn(t *testing.T) {
+	file := testEventFile(t)
+
+	tests := []struct {
+		name  string
+		data  interface{}
+		opts  []Option
+		paths []string
+		types map[string]interface{}
+	}{
+		{
+			name: "interface field",
+			data: []struct {
+				Shape testShape
+			}{{Shape: testCircle{R: 1}}, {Shape: &testSquare{S: 2}}},
+			paths: []string{"/Shape/$type", "/Shape/R", "/Shape/S"},
+			types: map[string]interface{}{"/Shape/$type": "struct2csv.testCircle"},
+		},
+		{
+			name:  "slice of interfaces",
+			data:  []testShape{testCircle{R: 1}, &testSquare{S: 2}},
+			paths: []string{"/R", "/S"},
+		},
+		{
+			name:  "oneof",
+			data:  []proto.Message{newTestEvent(t, file, `{"point": {"x": 1}}`)},
+			paths: []string{"/payload/$type", "/point/x"},
+			types: map[string]interface{}{"/payload/$type": "point"},
+		},
+		{
+			name:  "unset oneof",
+			data:  []proto.Message{newTestEvent(t, file, `{"id": "e1"}`)},
+			opts:  []Option{WithEmitUnpopulated(true)},
+			paths: []string{"/created_at", "/id", "/payload/$type", "/point/x", "/point/y", "/retries", "/text"},
+			types: map[string]interface{}{"/payload/$type": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), append(tt.opts, WithTypeColumn(true))...)
+			got, err := conv.Convert(tt.data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			paths := got.GetUnEncodedSortHeader()
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Convert() got UnEncodedSortHeader = %v, want %v", paths, tt.paths)
+			}
+			for path, want := range tt.types {
+				if v, _ := got.getKVElem(0).Get(got.GetMapping()[path]); v != want {
+					t.Errorf("Convert() got %s = %v, want %v", path, v, want)
+				}
+			}
+		})
+	}
+}
+
+// testEventFile builds the descriptor of
+//
+//	syntax = "proto3";
+//	package struct2csv.test;
+//
+//	extend google.protobuf.FieldOptions { string column = 50000; }
+//
+//	message Event {
+//	  string id = 1 [(column) = "Event ID"];
+//	  int64 created_at = 2;
+//	  optional int32 retries = 3;
+//	  oneof payload {
+//	    string text = 4;
+//	    Point point = 5;
+//	  }
+//	  repeated string tags = 6;
+//	}
+//
+//	message Point {
+//	  int32 x = 1;
+//	  int32 y = 2;
+//	}
+func testEventFile(t testing.TB) protoreflect.FileDescriptor {
+	t.Helper()
+
+	idOpts := &descriptorpb.FieldOptions{}
+	idOpts.ProtoReflect().SetUnknown(protowire.AppendString(protowire.AppendTag(nil, 50000, protowire.BytesType), "Event ID"))
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(protoJSONName(name)),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	inOneof := func(f *descriptorpb.FieldDescriptorProto, index int32) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(index)
+		return f
+	}
+
+	id := field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	id.Options = idOpts
+	retries := inOneof(field("retries", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32), 1)
+	retries.Proto3Optional = proto.Bool(true)
+	point := inOneof(field("point", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), 0)
+	point.TypeName = proto.String(".struct2csv.test.Point")
+	tags := field("tags", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	column := field("column", 50000, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	column.Extendee = proto.String(".google.protobuf.FieldOptions")
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("struct2csv/test/event.proto"),
+		Package:    proto.String("struct2csv.test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension:  []*descriptorpb.FieldDescriptorProto{column},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Event"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					id,
+					field("created_at", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					retries,
+					inOneof(field("text", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING), 0),
+					point,
+					tags,
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("payload")},
+					{Name: proto.String("_retries")},
+				},
+			},
+			{
+				Name: proto.String("Point"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("x", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+					field("y", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				},
+			},
+		},
+	}
+
+	file, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// newTestEvent returns a dynamic struct2csv.test.Event decoded from protojson
+func newTestEvent(t testing.TB, file protoreflect.FileDescriptor, js string) proto.Message {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(file.Messages().ByName("Event"))
+	if err := protojson.Unmarshal([]byte(js), msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func protoJSONName(name string) string {
+	b := make([]byte, 0, len(name))
+	upper := false
+	for i := 0; i < len(name); i++ {
+		if name[i] == '_' {
+			upper = true
+			continue
+		}
+		if upper && name[i] >= 'a' && name[i] <= 'z' {
+			b = append(b, name[i]-'a'+'A')
+		} else {
+			b = append(b, name[i])
+		}
+		upper = false
+	}
+	return string(b)
+}
@@ -1,19 +1,99 @@
 package struct2csv
 
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HeaderAutoIncrementConv numbers the paths in the order they are converted,
+// the zero value is ready to use
 type HeaderAutoIncrementConv struct {
-	max uint64
+	max   uint64
+	known map[string]KeyAutoIncrementID // every path converted or loaded so far, nil until the first one
 }
 
 func NewHeaderAutoIncrementConv() *HeaderAutoIncrementConv {
-	return &HeaderAutoIncrementConv{}
+	return &HeaderAutoIncrementConv{known: make(map[string]KeyAutoIncrementID)}
+}
+
+// NewHeaderAutoIncrementConvFromMapping returns a HeaderAutoIncrementConv
+// which keeps the IDs of a mapping written by CSVWriter.WriteMapping,
+// so the numeric headers mean the same across runs
+func NewHeaderAutoIncrementConvFromMapping(r io.Reader) (*HeaderAutoIncrementConv, error) {
+	h := NewHeaderAutoIncrementConv()
+	if err := h.LoadMapping(r); err != nil {
+		return nil, err
+	}
+	return h, nil
 }
 
 func (h *HeaderAutoIncrementConv) ConvertHeader(s string) KeyType {
+	if id, ok := h.known[s]; ok {
+		return id
+	}
+
 	hs := newKeyAuto(h.max)
 	h.max = uint64(hs)
+	h.remember(s, hs)
 	return hs
 }
 
+// LoadMapping adds the path and ID pairs of a mapping read by ReadMapping,
+// new paths get IDs above the largest one loaded
+func (h *HeaderAutoIncrementConv) LoadMapping(r io.Reader) error {
+	m, err := ReadMapping(r)
+	if err != nil {
+		return err
+	}
+
+	for path, key := range m.keys {
+		id, ok := key.(KeyAutoIncrementID)
+		if !ok {
+			return fmt.Errorf("LoadMapping: path %s has the non numeric code %s", path, key)
+		}
+		h.remember(path, id)
+		if uint64(id) > h.max {
+			h.max = uint64(id)
+		}
+	}
+
+	return nil
+}
+
+func (h *HeaderAutoIncrementConv) remember(path string, id KeyAutoIncrementID) {
+	if h.known == nil {
+		h.known = make(map[string]KeyAutoIncrementID)
+	}
+	h.known[path] = id
+}
+
+// WriteMapping writes every path converted or loaded so far in the format of
+// CSVWriter.WriteMapping, including paths the last conversion did not meet
+func (h *HeaderAutoIncrementConv) WriteMapping(w io.Writer) error {
+	paths := make([]string, 0, len(h.known))
+	for path := range h.known {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	codes := make([]string, 0, len(paths))
+	for _, path := range paths {
+		codes = append(codes, h.known[path].String())
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll([][]string{paths, codes}); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
 type HeaderOriginalStringConv struct {
 }
 
@@ -24,3 +104,278 @@ func NewHeaderOriginalStringConv() *HeaderOriginalStringConv {
 func (h *HeaderOriginalStringConv) ConvertHeader(s string) KeyType {
 	return newKeyString(s)
 }
+
+// HeaderDictionaryConv converts paths to human friendly labels looked up in a dictionary.
+// a dictionary key is a path, or a path pattern like WithInclude where "*" matches one
+// segment and "**" any number of them. the label can refer to the segments matched by
+// the first, second and third wildcard segment as {i}, {j} and {k},
+// e.g. "/Items/*/Price" => "Item {i} Price".
+// exact paths win over patterns, patterns with fewer wildcards win over the others.
+// paths without a dictionary entry are converted by the fallback converter.
+// two paths getting the same label would share a column, Err reports it
+type HeaderDictionaryConv struct {
+	exact    map[string]string
+	patterns []dictionaryPattern
+	fallback HeaderConverter
+	paths    map[string]string // label => path
+	err      error
+}
+
+type dictionaryPattern struct {
+	pattern   pathPattern
+	wildcards int
+	label     string
+}
+
+var dictionaryPlaceholders = [...]string{"{i}", "{j}", "{k}"}
+
+// NewHeaderDictionaryConv returns a HeaderDictionaryConv of dict,
+// fallback defaults to HeaderOriginalStringConv if nil
+func NewHeaderDictionaryConv(dict map[string]string, fallback HeaderConverter) (*HeaderDictionaryConv, error) {
+	if fallback == nil {
+		fallback = NewHeaderOriginalStringConv()
+	}
+
+	h := &HeaderDictionaryConv{
+		exact:    make(map[string]string, len(dict)),
+		fallback: fallback,
+		paths:    make(map[string]string),
+	}
+	for path, label := range dict {
+		if !hasPatternSyntax(path) {
+			h.exact[path] = label
+			continue
+		}
+
+		pattern, err := compilePathPattern(path)
+		if err != nil {
+			return nil, err
+		}
+		h.patterns = append(h.patterns, dictionaryPattern{pattern: pattern, wildcards: pattern.wildcards(), label: label})
+	}
+
+	sort.Slice(h.patterns, func(i, j int) bool {
+		pi, pj := h.patterns[i], h.patterns[j]
+		if pi.wildcards != pj.wildcards {
+			return pi.wildcards < pj.wildcards
+		}
+		return strings.Join(pi.pattern, string(separator)) < strings.Join(pj.pattern, string(separator))
+	})
+
+	return h, nil
+}
+
+// NewHeaderDictionaryConvFromCSV reads the dictionary from CSV rows of path and label,
+// lines starting with '#' are comments
+func NewHeaderDictionaryConvFromCSV(r io.Reader, fallback HeaderConverter) (*HeaderDictionaryConv, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]string, len(records))
+	for _, record := range records {
+		dict[record[0]] = record[1]
+	}
+	return NewHeaderDictionaryConv(dict, fallback)
+}
+
+func (h *HeaderDictionaryConv) ConvertHeader(s string) KeyType {
+	header := h.convert(s)
+	label := header.String()
+	if path, ok := h.paths[label]; ok && path != s {
+		if h.err == nil {
+			h.err = fmt.Errorf("HeaderDictionaryConv: %s and %s both get the label %q, use {i} to tell them apart", path, s, label)
+		}
+	} else {
+		h.paths[label] = s
+	}
+	return header
+}
+
+// Err returns the first label given to two paths, or the error of the fallback converter
+func (h *HeaderDictionaryConv) Err() error {
+	if h.err != nil {
+		return h.err
+	}
+	if conv, ok := h.fallback.(interface{ Err() error }); ok {
+		return conv.Err()
+	}
+	return nil
+}
+
+func (h *HeaderDictionaryConv) convert(s string) KeyType {
+	if label, ok := h.exact[s]; ok {
+		return newKeyString(label)
+	}
+
+	segs := splitPath(s)
+	for _, p := range h.patterns {
+		if label, ok := p.apply(segs); ok {
+			return newKeyString(label)
+		}
+	}
+
+	return h.fallback.ConvertHeader(s)
+}
+
+// apply returns the label of segs if the pattern matches them
+func (p dictionaryPattern) apply(segs []string) (string, bool) {
+	captured, ok := p.pattern.capture(segs)
+	if !ok {
+		return "", false
+	}
+
+	label := p.label
+	for i, seg := range captured {
+		if i == len(dictionaryPlaceholders) {
+			break
+		}
+		label = strings.ReplaceAll(label, dictionaryPlaceholders[i], seg)
+	}
+	return label, true
+}
+
+// CaseStyle is the identifier style of HeaderCaseConv
+type CaseStyle int
+
+const (
+	SnakeCase CaseStyle = iota // "/B2/0/B21" => "b2_0_b21"
+	CamelCase                  // "/B2/0/B21" => "b20B21"
+	TitleCase                  // "/B2/0/B21" => "B2 0 B21"
+	KebabCase                  // "/B2/0/B21" => "b2-0-b21"
+)
+
+// CollisionPolicy decides what HeaderCaseConv does when two paths give the same header
+type CollisionPolicy int
+
+const (
+	CollisionSuffix CollisionPolicy = iota // add a counter to the later header, e.g. "a_b_2"
+	CollisionError                         // fail the conversion, see HeaderCaseConv.Err
+)
+
+// HeaderCaseConv converts paths to identifiers of a CaseStyle, e.g. for SQL loaders
+// which can not use "/B2/0/B21" as a column name.
+// the path is split into words at separators, underscores, hyphens and camel case humps
+type HeaderCaseConv struct {
+	style     CaseStyle
+	collision CollisionPolicy
+	headers   map[string]string // path => header
+	paths     map[string]string // header => path
+	err       error
+}
+
+// NewHeaderCaseConv returns a HeaderCaseConv
+func NewHeaderCaseConv(style CaseStyle, collision CollisionPolicy) *HeaderCaseConv {
+	return &HeaderCaseConv{
+		style:     style,
+		collision: collision,
+		headers:   make(map[string]string),
+		paths:     make(map[string]string),
+	}
+}
+
+func (h *HeaderCaseConv) ConvertHeader(s string) KeyType {
+	if header, ok := h.headers[s]; ok {
+		return newKeyString(header)
+	}
+
+	base := h.format(pathWords(s))
+	header := base
+	for n := 2; ; n++ {
+		other, ok := h.paths[header]
+		if !ok {
+			break
+		}
+		if h.collision == CollisionError {
+			if h.err == nil {
+				h.err = fmt.Errorf("HeaderCaseConv: paths %s and %s both convert to %s", other, s, base)
+			}
+			break
+		}
+		header = h.format(append(pathWords(s), strconv.Itoa(n)))
+	}
+
+	h.headers[s] = header
+	h.paths[header] = s
+	return newKeyString(header)
+}
+
+// Err returns the first collision met with CollisionError
+func (h *HeaderCaseConv) Err() error {
+	return h.err
+}
+
+func (h *HeaderCaseConv) format(words []string) string {
+	var b strings.Builder
+	for i, w := range words {
+		switch h.style {
+		case SnakeCase, KebabCase:
+			if i > 0 {
+				if h.style == SnakeCase {
+					b.WriteByte('_')
+				} else {
+					b.WriteByte('-')
+				}
+			}
+			b.WriteString(strings.ToLower(w))
+		case CamelCase:
+			if i == 0 {
+				b.WriteString(strings.ToLower(w))
+			} else {
+				b.WriteString(upperFirst(strings.ToLower(w)))
+			}
+		case TitleCase:
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(upperFirst(w))
+		}
+	}
+	return b.String()
+}
+
+// pathWords splits a path into words, "/items/0/createdAt" => "items", "0", "created", "At"
+func pathWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// "createdAt" and the "S" of "HTTPServer" start a new word
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) && nextLower || unicode.IsUpper(prev) && nextLower {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
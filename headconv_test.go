@@ -0,0 +1,224 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaderDictionaryConv_ConvertHeader(t *testing.T) {
+	conv, err := NewHeaderDictionaryConvFromCSV(strings.NewReader(`# path,label
+/ID,Invoice ID
+/Items/*/Price,Item {i} Price
+/Items/0/Price,First Price
+/Items/*/Taxes/*,Item {i} Tax {j}
+/Meta/**,Meta {i}
+/Flag[AB],Flag {i}
+/Code?,Code
+`), nil)
+	if err != nil {
+		t.Fatalf("NewHeaderDictionaryConvFromCSV() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/ID", want: "Invoice ID"},
+		{path: "/Items/0/Price", want: "First Price"},
+		{path: "/Items/3/Price", want: "Item 3 Price"},
+		{path: "/Items/3/Taxes/1", want: "Item 3 Tax 1"},
+		{path: "/Items/3/Name", want: "/Items/3/Name"},
+		{path: "/Meta/a/b", want: "Meta a/b"},
+		{path: "/FlagB", want: "Flag FlagB"},
+		{path: "/FlagC", want: "/FlagC"},
+		{path: "/Code1", want: "Code"},
+		{path: "/Code12", want: "/Code12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := conv.ConvertHeader(tt.path).String(); got != tt.want {
+				t.Errorf("ConvertHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderDictionaryConv_Fallback(t *testing.T) {
+	conv, err := NewHeaderDictionaryConv(map[string]string{"/A1": "a"}, NewHeaderAutoIncrementConv())
+	if err != nil {
+		t.Fatalf("NewHeaderDictionaryConv() error = %v", err)
+	}
+
+	if got := conv.ConvertHeader("/A2").String(); got != "1" {
+		t.Errorf("ConvertHeader() = %v, want 1", got)
+	}
+	if _, err := NewHeaderDictionaryConv(map[string]string{"Items/*": "a"}, nil); err == nil {
+		t.Error("NewHeaderDictionaryConv() error = nil, want error")
+	}
+}
+
+func TestHeaderDictionaryConv_DuplicateLabel(t *testing.T) {
+	type item struct {
+		Price int
+	}
+	type invoice struct {
+		Items []item
+	}
+
+	tests := []struct {
+		name    string
+		dict    map[string]string
+		header  []string
+		wantErr bool
+	}{
+		{
+			name:    "pattern without placeholder",
+			dict:    map[string]string{"/Items/*/Price": "Price"},
+			wantErr: true,
+		},
+		{
+			name:   "pattern with placeholder",
+			dict:   map[string]string{"/Items/*/Price": "Price {i}"},
+			header: []string{"Price 0", "Price 1"},
+		},
+		{
+			name:    "exact paths",
+			dict:    map[string]string{"/Items/0/Price": "Price", "/Items/1/Price": "Price"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict, err := NewHeaderDictionaryConv(tt.dict, nil)
+			if err != nil {
+				t.Fatalf("NewHeaderDictionaryConv() error = %v", err)
+			}
+			conv, _ := NewStructConverter(dict)
+			kvs, err := conv.Convert([]invoice{{Items: []item{{1}, {2}}}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Convert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := kvs.GetEncodedSortHeader(); strings.Join(got, ",") != strings.Join(tt.header, ",") {
+				t.Errorf("GetEncodedSortHeader() = %v, want %v", got, tt.header)
+			}
+		})
+	}
+}
+
+func TestHeaderCaseConv_ConvertHeader(t *testing.T) {
+	tests := []struct {
+		style CaseStyle
+		path  string
+		want  string
+	}{
+		{style: SnakeCase, path: "/B2/0/B21", want: "b2_0_b21"},
+		{style: SnakeCase, path: "/items/0/createdAt", want: "items_0_created_at"},
+		{style: SnakeCase, path: "/HTTPServer/max_conns", want: "http_server_max_conns"},
+		{style: CamelCase, path: "/items/0/created_at", want: "items0CreatedAt"},
+		{style: TitleCase, path: "/items/0/createdAt", want: "Items 0 Created At"},
+		{style: KebabCase, path: "/B2/0/B21", want: "b2-0-b21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := NewHeaderCaseConv(tt.style, CollisionSuffix).ConvertHeader(tt.path).String(); got != tt.want {
+				t.Errorf("ConvertHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderCaseConv_Collision(t *testing.T) {
+	conv := NewHeaderCaseConv(SnakeCase, CollisionSuffix)
+	got := []string{
+		conv.ConvertHeader("/a/b").String(),
+		conv.ConvertHeader("/a_b").String(),
+		conv.ConvertHeader("/aB").String(),
+		conv.ConvertHeader("/a_b").String(),
+	}
+	want := []string{"a_b", "a_b_2", "a_b_3", "a_b_2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ConvertHeader() = %v, want %v", got, want)
+	}
+
+	strict, _ := NewStructConverter(NewHeaderCaseConv(SnakeCase, CollisionError))
+	if _, err := strict.Convert([]map[string]int{{"a_b": 1, "aB": 2}}); err == nil {
+		t.Error("Convert() error = nil, want collision error")
+	}
+}
+
+func TestHeaderAutoIncrementConv_LoadMapping(t *testing.T) {
+	first, _ := NewStructConverter(NewHeaderAutoIncrementConv(), WithIsObjArray(false))
+	kvs, err := first.Convert(map[string]int{"b": 1, "c": 2})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	var mapping strings.Builder
+	if err := NewCSVWriter(&mapping).WriteMapping(kvs); err != nil {
+		t.Fatalf("WriteMapping() error = %v", err)
+	}
+	previous := kvs.GetMapping()
+
+	headerConv, err := NewHeaderAutoIncrementConvFromMapping(strings.NewReader(mapping.String()))
+	if err != nil {
+		t.Fatalf("NewHeaderAutoIncrementConvFromMapping() error = %v", err)
+	}
+	second, _ := NewStructConverter(headerConv, WithIsObjArray(false))
+	kvs, err = second.Convert(map[string]int{"a": 1, "c": 2})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	got := kvs.GetMapping()
+	if got["/c"] != previous["/c"] {
+		t.Errorf("Convert() got /c = %v, want %v", got["/c"], previous["/c"])
+	}
+	if got["/a"].String() != "3" {
+		t.Errorf("Convert() got /a = %v, want 3", got["/a"])
+	}
+
+	var updated strings.Builder
+	if err := headerConv.WriteMapping(&updated); err != nil {
+		t.Fatalf("WriteMapping() error = %v", err)
+	}
+	want := "/a,/b,/c\n3," + previous["/b"].String() + "," + previous["/c"].String() + "\n"
+	if updated.String() != want {
+		t.Errorf("WriteMapping() = %q, want %q", updated.String(), want)
+	}
+}
+
+func TestHeaderAutoIncrementConv_ZeroValue(t *testing.T) {
+	var empty strings.Builder
+	if err := (&HeaderAutoIncrementConv{}).WriteMapping(&empty); err != nil {
+		t.Fatalf("WriteMapping() error = %v", err)
+	}
+
+	type row struct {
+		A, B int
+	}
+	headerConv := &HeaderAutoIncrementConv{}
+	conv, _ := NewStructConverter(headerConv)
+	if _, err := conv.Convert([]row{{1, 2}}); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	var mapping strings.Builder
+	if err := headerConv.WriteMapping(&mapping); err != nil {
+		t.Fatalf("WriteMapping() error = %v", err)
+	}
+	if want := "/A,/B\n1,2\n"; mapping.String() != want {
+		t.Errorf("WriteMapping() = %q, want %q", mapping.String(), want)
+	}
+
+	loaded := &HeaderAutoIncrementConv{}
+	if err := loaded.LoadMapping(strings.NewReader(mapping.String())); err != nil {
+		t.Fatalf("LoadMapping() error = %v", err)
+	}
+	if got := loaded.ConvertHeader("/c").String(); got != "3" {
+		t.Errorf("ConvertHeader() = %v, want 3", got)
+	}
+}
@@ -0,0 +1,102 @@
+package struct2csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// headerLevelRows returns the header rows of WithHeaderLevels: row l holds the segment l
+// of every path, blank where a parent is shared with the column on the left or the path
+// has ended. the last segment of a path is always written
+func headerLevelRows(paths []string) [][]string {
+	levels := headerLevels(paths)
+	rows := make([][]string, 0, len(levels))
+	for _, level := range levels {
+		row := make([]string, len(paths))
+		for _, cell := range level {
+			row[cell.column] = cell.label
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ParseHeaderLevels returns the paths of the header rows written with WithHeaderLevels.
+// the last non blank cell of a column is the end of its path, a blank cell above it
+// repeats the segment of the column on the left
+func ParseHeaderLevels(rows [][]string) ([]string, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("ParseHeaderLevels: no header rows")
+	}
+	width := len(rows[0])
+	for i, row := range rows {
+		if len(row) != width {
+			return nil, fmt.Errorf("ParseHeaderLevels: row %d has %d fields, want %d", i+1, len(row), width)
+		}
+	}
+
+	paths := make([]string, 0, width)
+	var left []string
+	for c := 0; c < width; c++ {
+		last := -1
+		for l := range rows {
+			if rows[l][c] != "" {
+				last = l
+			}
+		}
+		if last < 0 {
+			return nil, fmt.Errorf("ParseHeaderLevels: column %d is blank", c+1)
+		}
+
+		segs := make([]string, last+1)
+		for l := 0; l <= last; l++ {
+			segs[l] = rows[l][c]
+			if segs[l] != "" {
+				continue
+			}
+			if l >= len(left) {
+				return nil, fmt.Errorf("ParseHeaderLevels: column %d level %d is blank with no parent on the left", c+1, l+1)
+			}
+			segs[l] = left[l]
+		}
+
+		paths = append(paths, string(separator)+strings.Join(segs, string(separator)))
+		left = segs
+	}
+	return paths, nil
+}
+
+// ReadHeaderLevels reads the header rows written with WithHeaderLevels up to the blank row
+// which ends them, and returns the paths of the columns. r is left at the first data row
+func ReadHeaderLevels(r *csv.Reader) ([]string, error) {
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil, errors.New("ReadHeaderLevels: no blank row ends the header")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ReadHeaderLevels: %w", err)
+		}
+		if isBlankRecord(record) {
+			break
+		}
+		rows = append(rows, record)
+	}
+
+	return ParseHeaderLevels(rows)
+}
+
+// isBlankRecord reports whether every field of record is empty, no header row is:
+// the first column holding a level writes its segment
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if field != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package struct2csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_headerLevelRows(t *testing.T) {
+	paths := []string{"/A", "/B2/0/B21", "/B2/0/B22", "/B2/1/B21", "/C/D"}
+	want := [][]string{
+		{"A", "B2", "", "", "C"},
+		{"", "0", "", "1", "D"},
+		{"", "B21", "B22", "B21", ""},
+	}
+	if got := headerLevelRows(paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("headerLevelRows() = %v, want %v", got, want)
+	}
+}
+
+func TestParseHeaderLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    [][]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "nested",
+			rows: [][]string{
+				{"A", "B2", "", "", "C"},
+				{"", "0", "", "1", "D"},
+				{"", "B21", "B22", "B21", ""},
+			},
+			want: []string{"/A", "/B2/0/B21", "/B2/0/B22", "/B2/1/B21", "/C/D"},
+		},
+		{
+			name: "parent and child columns",
+			rows: [][]string{{"A", "A", ""}, {"", "B", "C"}},
+			want: []string{"/A", "/A/B", "/A/C"},
+		},
+		{
+			name:    "no parent on the left",
+			rows:    [][]string{{"", "A"}, {"B", ""}},
+			wantErr: true,
+		},
+		{
+			name:    "blank column",
+			rows:    [][]string{{"A", ""}, {"B", ""}},
+			wantErr: true,
+		},
+		{
+			name:    "ragged rows",
+			rows:    [][]string{{"A", "B"}, {"C"}},
+			wantErr: true,
+		},
+		{
+			name:    "no rows",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeaderLevels(tt.rows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaderLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHeaderLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriter_WithHeaderLevels(t *testing.T) {
+	type item struct {
+		Name string
+		Qty  int
+	}
+	type order struct {
+		ID    string
+		Items []item
+	}
+	data := []order{{ID: "o1", Items: []item{{"x", 1}, {"y", 2}}}, {ID: "o2", Items: []item{{"z", 3}}}}
+
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv(), WithInsertionOrder(true))
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	var paths []string
+	for _, c := range kvs.Columns() {
+		paths = append(paths, c.Path)
+	}
+
+	var buf bytes.Buffer
+	if err := NewCSVWriter(&buf, WithHeader(true), WithHeaderLevels(true)).WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "ID,Items,,,\n" +
+		",0,,1,\n" +
+		",Name,Qty,Name,Qty\n" +
+		",,,,\n" +
+		"o1,x,1,y,2\n" +
+		"o2,z,3,,\n"
+	if buf.String() != want {
+		t.Fatalf("WriteCSV() = \n%s\nwant\n%s", buf.String(), want)
+	}
+
+	r := csv.NewReader(strings.NewReader(buf.String()))
+	got, err := ReadHeaderLevels(r)
+	if err != nil {
+		t.Fatalf("ReadHeaderLevels() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("ReadHeaderLevels() = %v, want %v", got, paths)
+	}
+	if record, _ := r.Read(); !reflect.DeepEqual(record, []string{"o1", "x", "1", "y", "2"}) {
+		t.Errorf("first data row = %v", record)
+	}
+}
+
+func TestReadHeaderLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []string
+		next    []string
+		wantErr bool
+	}{
+		{name: "single column", csv: "A\nB\n\"\"\nx\n", want: []string{"/A/B"}, next: []string{"x"}},
+		{name: "blank data row", csv: "A,B\n,\n,\n", want: []string{"/A", "/B"}, next: []string{"", ""}},
+		{name: "no blank row", csv: "A,B\nx,y\n", wantErr: true},
+		{name: "no header rows", csv: ",\nx,y\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := csv.NewReader(strings.NewReader(tt.csv))
+			got, err := ReadHeaderLevels(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadHeaderLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadHeaderLevels() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if record, _ := r.Read(); !reflect.DeepEqual(record, tt.next) {
+				t.Errorf("first data row = %q, want %q", record, tt.next)
+			}
+		})
+	}
+}
+
+func TestCSVWriter_WithHeaderLevelsSingleColumn(t *testing.T) {
+	type row struct {
+		A struct{ B string }
+	}
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert([]row{{}, {A: struct{ B string }{"x"}}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewCSVWriter(&buf, WithHeaderLevels(true)).WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if want := "A\nB\n\"\"\nx\n"; buf.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,102 @@
+package struct2csv
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HTMLWriter writes an HTML table. with WithTableGroupHeader the header has one row per
+// path level, parents spanning their children with colspan
+type HTMLWriter struct {
+	w    *bufio.Writer
+	opts *tableOptions
+	buf  []byte
+}
+
+// NewHTMLWriter returns new HTMLWriter
+func NewHTMLWriter(w io.Writer, options ...TableOption) *HTMLWriter {
+	return &HTMLWriter{
+		w:    bufio.NewWriter(w),
+		opts: newTableOptions(options),
+		buf:  make([]byte, 0, 4096),
+	}
+}
+
+// WriteHTML writes the table of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *HTMLWriter) WriteHTML(results *KVs) error {
+	columns := results.Columns()
+
+	w.buf = append(w.buf[:0], "<table>\n<thead>\n"...)
+	if w.opts.groupHeader {
+		paths := make([]string, 0, len(columns))
+		for _, c := range columns {
+			paths = append(paths, c.Path)
+		}
+		for _, level := range headerLevels(paths) {
+			w.buf = append(w.buf, "<tr>"...)
+			for _, cell := range level {
+				w.buf = append(w.buf, "<th"...)
+				if cell.colspan > 1 {
+					w.buf = append(w.buf, ` colspan="`...)
+					w.buf = strconv.AppendInt(w.buf, int64(cell.colspan), 10)
+					w.buf = append(w.buf, '"')
+				}
+				if cell.rowspan > 1 {
+					w.buf = append(w.buf, ` rowspan="`...)
+					w.buf = strconv.AppendInt(w.buf, int64(cell.rowspan), 10)
+					w.buf = append(w.buf, '"')
+				}
+				w.buf = append(w.buf, '>')
+				w.buf = w.appendText(w.buf, cell.label)
+				w.buf = append(w.buf, "</th>"...)
+			}
+			w.buf = append(w.buf, "</tr>\n"...)
+		}
+	} else {
+		w.buf = append(w.buf, "<tr>"...)
+		for _, c := range columns {
+			w.buf = append(w.buf, "<th>"...)
+			w.buf = w.appendText(w.buf, w.opts.label(c))
+			w.buf = append(w.buf, "</th>"...)
+		}
+		w.buf = append(w.buf, "</tr>\n"...)
+	}
+	w.buf = append(w.buf, "</thead>\n<tbody>\n"...)
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		w.buf = append(w.buf[:0], "<tr>"...)
+		for i, v := range values {
+			if isNumericType(columns[i].Type) {
+				w.buf = append(w.buf, `<td align="right">`...)
+			} else {
+				w.buf = append(w.buf, "<td>"...)
+			}
+			w.buf = w.appendText(w.buf, displayString(v))
+			w.buf = append(w.buf, "</td>"...)
+		}
+		w.buf = append(w.buf, "</tr>\n"...)
+		_, err := w.w.Write(w.buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	w.w.WriteString("</tbody>\n</table>\n")
+	return w.w.Flush()
+}
+
+// appendText appends s truncated and escaped, newlines become <br>
+func (w *HTMLWriter) appendText(b []byte, s string) []byte {
+	s = html.EscapeString(w.opts.truncate(s))
+	if strings.ContainsAny(s, "\r\n") {
+		s = strings.NewReplacer("\r\n", "<br>", "\n", "<br>", "\r", "<br>").Replace(s)
+	}
+	return append(b, s...)
+}
@@ -0,0 +1,61 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLWriter_WriteHTML(t *testing.T) {
+	type inner struct {
+		X int
+		Y string
+	}
+	type row struct {
+		ID    string
+		Point inner
+	}
+	data := []row{{ID: "<a&b>", Point: inner{X: 1, Y: "y\nz"}}}
+
+	tests := []struct {
+		name string
+		opts []TableOption
+		want string
+	}{
+		{
+			name: "single header row",
+			want: "<table>\n<thead>\n" +
+				"<tr><th>/ID</th><th>/Point/X</th><th>/Point/Y</th></tr>\n" +
+				"</thead>\n<tbody>\n" +
+				"<tr><td>&lt;a&amp;b&gt;</td><td align=\"right\">1</td><td>y<br>z</td></tr>\n" +
+				"</tbody>\n</table>\n",
+		},
+		{
+			name: "grouped header",
+			opts: []TableOption{WithTableGroupHeader(true), WithTableMaxWidth(3)},
+			want: "<table>\n<thead>\n" +
+				"<tr><th rowspan=\"2\">ID</th><th colspan=\"2\">Po…</th></tr>\n" +
+				"<tr><th>X</th><th>Y</th></tr>\n" +
+				"</thead>\n<tbody>\n" +
+				"<tr><td>&lt;a…</td><td align=\"right\">1</td><td>y<br>z</td></tr>\n" +
+				"</tbody>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewHTMLWriter(&b, tt.opts...).WriteHTML(kvs); err != nil {
+				t.Fatalf("WriteHTML() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteHTML() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package struct2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// jsonObject is a decoded JSON object which keeps the order of its keys
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{} // nil, bool, string, json.Number, []interface{} or jsonObject
+}
+
+var (
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// ConvertJSON converts one JSON document read from r.
+// a top level array of objects gives one row per element, any other value a single row,
+// with WithIsObjArray(true) every element of a top level array is a row.
+// numbers keep their original text as json.Number, and the header follows the key
+// order of the document unless WithInsertionOrder(false) is given.
+// members holding false, 0 or "" are kept, unlike zero struct fields, and null is left out
+func (s *StructConverter) ConvertJSON(r io.Reader) (*KVs, error) {
+	s.useJSONOrder()
+	dec := newJSONDecoder(r)
+	doc, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("ConvertJSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("ConvertJSON: unexpected data after top level value")
+	}
+
+	rows, ok := doc.([]interface{})
+	if !ok || len(rows) == 0 {
+		rows = []interface{}{doc}
+	} else if _, isObj := rows[0].(jsonObject); !isObj && !(s.opts.objArraySet && s.opts.isObjArray) {
+		rows = []interface{}{doc}
+	}
+
+	for _, row := range rows {
+		if err := s.appendJSONRow(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.headerConvErr(); err != nil {
+		return nil, err
+	}
+	return s.kvs, nil
+}
+
+// ConvertNDJSON converts a stream of JSON values read from r, one row per value,
+// the values are usually separated by newlines but any whitespace works.
+// the header follows the key order like ConvertJSON
+func (s *StructConverter) ConvertNDJSON(r io.Reader) (*KVs, error) {
+	s.useJSONOrder()
+	dec := newJSONDecoder(r)
+	for line := 1; ; line++ {
+		row, err := decodeJSONValue(dec)
+		if err == io.EOF {
+			if err := s.headerConvErr(); err != nil {
+				return nil, err
+			}
+			return s.kvs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ConvertNDJSON: value %d: %w", line, err)
+		}
+
+		if err := s.appendJSONRow(row); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// useJSONOrder switches to insertion order for JSON input, the key order of a document
+// is meaningful unlike the order of a Go map. a converter which already holds
+// columns keeps its order
+func (s *StructConverter) useJSONOrder() {
+	if !s.opts.orderSet && len(s.kvs.mapping) == 0 {
+		s.kvs.insertionOrder = true
+	}
+}
+
+func (s *StructConverter) appendJSONRow(row interface{}) error {
+	result := newKeyValue(s.opts.rowSize)
+	if err := s.flattenJSONValue(result, row, NewPathBuilder(s.opts.strBuilderCap)); err != nil {
+		return err
+	}
+	s.kvs.rows++
+	if result.Len() > 0 {
+		s.kvs.appendElem(result)
+	}
+	return nil
+}
+
+func newJSONDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec
+}
+
+// decodeJSONValue reads the next value of dec, io.EOF means there is none
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			obj = append(obj, jsonMember{key: keyTok.(string), value: value})
+		}
+		if _, err := dec.Token(); err != nil { // '}'
+			return nil, unexpectedEOF(err)
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil { // ']'
+			return nil, unexpectedEOF(err)
+		}
+		return arr, nil
+	default:
+		return tok, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// flattenJSONValue flattens a value of decodeJSONValue. unlike a zero struct field,
+// a member holding false, 0 or "" is data and kept, only null is left out
+func (s *StructConverter) flattenJSONValue(out *KeyValue, v interface{}, key PathBuilder) error {
+	switch v := v.(type) {
+	case jsonObject:
+		for _, m := range v {
+			if err := s.flattenJSONMember(out, m.key, m.value, key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if err := s.flattenJSONMember(out, strconv.Itoa(i), e, key); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default: // bool, string or json.Number
+		s.set(out, key.String(), v)
+	}
+	return nil
+}
+
+func (s *StructConverter) flattenJSONMember(out *KeyValue, name string, v interface{}, prefix PathBuilder) error {
+	if v == nil {
+		return nil
+	}
+
+	pointer := prefix.Clone(s.opts.strBuilderCap)
+	pointer.AppendString(name)
+	if !s.visit(pointer) {
+		return nil
+	}
+	return s.flattenJSONValue(out, v, pointer)
+}
+
+// isJSONNumber reports whether s follows the JSON number grammar. a json.Number set by
+// hand may hold any text, writers check it before copying the text unquoted into SQL or JSON
+func isJSONNumber(s string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
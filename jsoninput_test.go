@@ -0,0 +1,158 @@
+package struct2csv
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStructConverter_ConvertJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		ndjson  bool
+		opts    []Option
+		paths   []string
+		rows    int
+		wantErr bool
+	}{
+		{
+			name:  "array of objects in key order",
+			input: `[{"z": "x", "a": [{"b": 1.10}], "c": true}, {"z": "y", "d": 2}]`,
+			paths: []string{"/z", "/a/0/b", "/c", "/d"},
+			rows:  2,
+		},
+		{
+			name:  "sorted",
+			input: `[{"z": "x", "a": [{"b": 1.10}], "c": true}, {"z": "y", "d": null}]`,
+			opts:  []Option{WithInsertionOrder(false)},
+			paths: []string{"/a/0/b", "/c", "/z"},
+			rows:  2,
+		},
+		{
+			name:  "single object",
+			input: `{"a": {"b": [1, 2]}}`,
+			paths: []string{"/a/b/0", "/a/b/1"},
+			rows:  1,
+		},
+		{
+			name:  "array of arrays",
+			input: `[[1, 2], [3]]`,
+			opts:  []Option{WithIsObjArray(false)},
+			paths: []string{"/0/0", "/0/1", "/1/0"},
+			rows:  1,
+		},
+		{
+			name:  "array of arrays as rows",
+			input: `[[1, 2], [3]]`,
+			opts:  []Option{WithIsObjArray(true)},
+			paths: []string{"/0", "/1"},
+			rows:  2,
+		},
+		{
+			name:   "ndjson",
+			input:  "{\"a\": 1}\n{\"b\": 2}\n\n",
+			ndjson: true,
+			paths:  []string{"/a", "/b"},
+			rows:   2,
+		},
+		{
+			name:    "truncated",
+			input:   `[{"a": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), append(tt.opts, WithResultCap(0))...)
+			convert := conv.ConvertJSON
+			if tt.ndjson {
+				convert = conv.ConvertNDJSON
+			}
+
+			got, err := convert(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if paths := got.GetUnEncodedSortHeader(); !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("ConvertJSON() got UnEncodedSortHeader = %v, want %v", paths, tt.paths)
+			}
+			if len(got.kvs) != tt.rows {
+				t.Errorf("ConvertJSON() got %d rows, want %d", len(got.kvs), tt.rows)
+			}
+		})
+	}
+}
+
+func TestStructConverter_ConvertJSONNumber(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), WithResultCap(0))
+	got, err := conv.ConvertJSON(strings.NewReader(`{"big": 12345678901234567890, "price": 1.10}`))
+	if err != nil {
+		t.Fatalf("ConvertJSON() error = %v", err)
+	}
+
+	for path, want := range map[string]json.Number{"/big": "12345678901234567890", "/price": "1.10"} {
+		if v, _ := got.getKVElem(0).Get(got.GetMapping()[path]); v != want {
+			t.Errorf("ConvertJSON() got %s = %#v, want %#v", path, v, want)
+		}
+	}
+}
+
+func Test_isJSONNumber(t *testing.T) {
+	tests := map[string]bool{
+		"0": true, "-12": true, "1.10": true, "1e3": true, "-0.5E-07": true, "12345678901234567890": true,
+		"": false, "-": false, "01": false, "1.": false, ".5": false, "1e": false, "+1": false,
+		"0x1p3": false, "NaN": false, "Inf": false, "1_000": false, " 1": false, "1); DROP TABLE data; --": false,
+	}
+	for s, want := range tests {
+		if got := isJSONNumber(s); got != want {
+			t.Errorf("isJSONNumber(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestStructConverter_ConvertJSONZeroValues(t *testing.T) {
+	const input = `[{"id": 1, "ok": false, "name": "", "n": 0, "tags": [false, null, ""]}, {"id": 2, "ok": true, "name": "b", "n": null}]`
+
+	tests := []struct {
+		name  string
+		write func(w io.Writer, kvs *KVs) error
+		want  string
+	}{
+		{
+			name:  "csv",
+			write: func(w io.Writer, kvs *KVs) error { return NewCSVWriter(w).WriteCSV(kvs) },
+			want:  "/id,/ok,/name,/n,/tags/0,/tags/2\n1,false,,0,false,\n2,true,b,,,\n",
+		},
+		{
+			name:  "sql",
+			write: func(w io.Writer, kvs *KVs) error { return NewSQLWriter(w, WithSQLCreateTable(false)).WriteSQL(kvs) },
+			want: "INSERT INTO \"data\" (\"/id\", \"/ok\", \"/name\", \"/n\", \"/tags/0\", \"/tags/2\") VALUES\n" +
+				"(1, false, '', 0, false, ''),\n" +
+				"(2, true, 'b', NULL, NULL, NULL);\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.ConvertJSON(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("ConvertJSON() error = %v", err)
+			}
+			var b strings.Builder
+			if err := tt.write(&b, kvs); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("wrote %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
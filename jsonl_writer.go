@@ -0,0 +1,141 @@
+package struct2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type jsonlOptions struct {
+	originalKeys bool // key the objects by path instead of encoded header
+	nulls        bool // write null for missing values instead of leaving the key out
+}
+
+type JSONLOption func(opts *jsonlOptions)
+
+// WithJSONLOriginalKeys keys the objects by path, e.g. "/B2/0/B21", instead of the encoded header
+func WithJSONLOriginalKeys(p bool) JSONLOption {
+	return func(opts *jsonlOptions) {
+		opts.originalKeys = p
+	}
+}
+
+// WithJSONLNulls writes every column in every object, missing values as null
+func WithJSONLNulls(p bool) JSONLOption {
+	return func(opts *jsonlOptions) {
+		opts.nulls = p
+	}
+}
+
+// JSONLWriter writes JSON Lines, one flat object per row with the header as keys.
+// values keep their type: numbers stay numbers and bools stay bools
+type JSONLWriter struct {
+	w    *bufio.Writer
+	opts *jsonlOptions
+	buf  []byte
+}
+
+// NewJSONLWriter returns new JSONLWriter
+func NewJSONLWriter(w io.Writer, options ...JSONLOption) *JSONLWriter {
+	opts := &jsonlOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &JSONLWriter{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+		buf:  make([]byte, 0, 4096),
+	}
+}
+
+// WriteJSONL writes the rows of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *JSONLWriter) WriteJSONL(results *KVs) error {
+	columns := results.Columns()
+	keys := make([][]byte, 0, len(columns))
+	for _, c := range columns {
+		name := c.Code
+		if w.opts.originalKeys {
+			name = c.Path
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		w.buf = append(w.buf[:0], '{')
+		first := true
+		for i, v := range values {
+			if v == nil && !w.opts.nulls {
+				continue
+			}
+			if !first {
+				w.buf = append(w.buf, ',')
+			}
+			first = false
+			w.buf = append(w.buf, keys[i]...)
+			w.buf = append(w.buf, ':')
+			w.buf = appendJSONValue(w.buf, v)
+		}
+		w.buf = append(w.buf, '}', '\n')
+
+		_, err := w.w.Write(w.buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
+
+// appendJSONValue appends v as typed JSON, floats JSON can not hold become strings
+func appendJSONValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case protoreflect.EnumNumber:
+		return strconv.AppendInt(b, int64(v), 10)
+	case float64:
+		return appendJSONFloat(b, v, 64)
+	case float32:
+		return appendJSONFloat(b, float64(v), 32)
+	case json.Number:
+		if !isJSONNumber(string(v)) {
+			s, _ := json.Marshal(string(v))
+			return append(b, s...)
+		}
+		return append(b, v...)
+	case string:
+		s, _ := json.Marshal(v)
+		return append(b, s...)
+	default:
+		s, err := json.Marshal(v)
+		if err != nil {
+			s, _ = json.Marshal(toString(v))
+		}
+		return append(b, s...)
+	}
+}
+
+func appendJSONFloat(b []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.AppendQuote(b, strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, bits)
+}
@@ -0,0 +1,79 @@
+package struct2csv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONLWriter_WriteJSONL(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+		Ok    bool
+		Score float64
+	}
+	data := []row{{Name: "a", Count: 1, Ok: true, Score: 0.5}, {Name: "b\"c"}}
+
+	tests := []struct {
+		name       string
+		headerConv HeaderConverter
+		opts       []JSONLOption
+		want       string
+	}{
+		{
+			name:       "original",
+			headerConv: NewHeaderOriginalStringConv(),
+			want:       "{\"/Count\":1,\"/Name\":\"a\",\"/Ok\":true,\"/Score\":0.5}\n{\"/Name\":\"b\\\"c\"}\n",
+		},
+		{
+			name:       "encoded with nulls",
+			headerConv: NewHeaderAutoIncrementConv(),
+			opts:       []JSONLOption{WithJSONLNulls(true)},
+			want:       "{\"1\":\"a\",\"2\":1,\"3\":true,\"4\":0.5}\n{\"1\":\"b\\\"c\",\"2\":null,\"3\":null,\"4\":null}\n",
+		},
+		{
+			name:       "encoded with original keys",
+			headerConv: NewHeaderAutoIncrementConv(),
+			opts:       []JSONLOption{WithJSONLOriginalKeys(true)},
+			want:       "{\"/Name\":\"a\",\"/Count\":1,\"/Ok\":true,\"/Score\":0.5}\n{\"/Name\":\"b\\\"c\"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(tt.headerConv)
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewJSONLWriter(&b, tt.opts...).WriteJSONL(kvs); err != nil {
+				t.Fatalf("WriteJSONL() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteJSONL() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLWriter_WriteJSONLNumber(t *testing.T) {
+	type row struct {
+		N json.Number
+	}
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert([]row{{N: "1.5e3"}, {N: `1,"x":2`}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewJSONLWriter(&b).WriteJSONL(kvs); err != nil {
+		t.Fatalf("WriteJSONL() error = %v", err)
+	}
+	if want := "{\"/N\":1.5e3}\n{\"/N\":\"1,\\\"x\\\":2\"}\n"; b.String() != want {
+		t.Errorf("WriteJSONL() = %q, want %q", b.String(), want)
+	}
+}
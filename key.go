@@ -24,6 +24,10 @@ type KVs struct {
 	mapping         map[string]KeyType
 	encodeHeaders   []string // mapping's value call there's String()
 	unEncodeHeaders []string // mapping's key
+	insertionOrder  bool     // the headers follow order instead of being sorted
+	order           []string // mapping's key in the order they were first seen
+	stats           map[string]*columnStats
+	rows            int // number of rows converted, the index of the row being converted
 }
 
 func NewKVs(size, preMappingSize int) *KVs {
@@ -33,6 +37,7 @@ func NewKVs(size, preMappingSize int) *KVs {
 		mapping:         make(map[string]KeyType, preMappingSize),
 		encodeHeaders:   make([]string, 0, preMappingSize),
 		unEncodeHeaders: make([]string, 0, preMappingSize),
+		stats:           make(map[string]*columnStats, preMappingSize),
 	}
 
 	for i := 0; i < size; i++ {
@@ -48,6 +53,9 @@ func (kvs *KVs) Reset() {
 	unH := (*reflect.SliceHeader)(unsafe.Pointer(&kvs.unEncodeHeaders))
 	unH.Len = 0
 	kvs.mapping = make(map[string]KeyType, kvs.preSize)
+	kvs.order = kvs.order[:0]
+	kvs.stats = make(map[string]*columnStats, kvs.preSize)
+	kvs.rows = 0
 }
 
 func (kvs *KVs) getKVElem(index int) *KeyValue {
@@ -64,6 +72,13 @@ func (kvs *KVs) GetMapping() map[string]KeyType {
 
 func (kvs *KVs) GetSortMappingValues() []KeyType {
 	vs := make([]KeyType, 0, len(kvs.mapping))
+	if kvs.insertionOrder {
+		for _, k := range kvs.order {
+			vs = append(vs, kvs.mapping[k])
+		}
+		return vs
+	}
+
 	for _, v := range kvs.mapping {
 		vs = append(vs, v)
 	}
@@ -80,6 +95,11 @@ func (kvs *KVs) GetUnEncodedSortHeader() []string {
 		return kvs.unEncodeHeaders
 	}
 
+	if kvs.insertionOrder {
+		kvs.unEncodeHeaders = append(kvs.unEncodeHeaders, kvs.order...)
+		return kvs.unEncodeHeaders
+	}
+
 	for unen := range kvs.mapping {
 		kvs.unEncodeHeaders = append(kvs.unEncodeHeaders, unen)
 	}
@@ -92,6 +112,13 @@ func (kvs *KVs) GetEncodedSortHeader() []string {
 		return kvs.encodeHeaders
 	}
 
+	if kvs.insertionOrder {
+		for _, k := range kvs.order {
+			kvs.encodeHeaders = append(kvs.encodeHeaders, kvs.mapping[k].String())
+		}
+		return kvs.encodeHeaders
+	}
+
 	for _, en := range kvs.mapping {
 		kvs.encodeHeaders = append(kvs.encodeHeaders, en.String())
 	}
@@ -171,12 +198,11 @@ func (k KeyString) String() string {
 }
 
 func (k KeyString) Int() uint64 {
-	sh := (*reflect.StringHeader)(unsafe.Pointer(&k))
-	sliceH := &reflect.SliceHeader{
-		Data: sh.Data,
-		Len:  sh.Len,
-		Cap:  sh.Len,
-	}
-	res := md5.Sum(*(*[]byte)(unsafe.Pointer(sliceH)))
+	// view the string as a byte slice without copying
+	b := *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		Cap int
+	}{string(k), len(k)}))
+	res := md5.Sum(b)
 	return *(*uint64)(unsafe.Pointer(&res)) // only use 8 byte
 }
@@ -0,0 +1,98 @@
+package struct2csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ltsvOptions struct {
+	emptyFields bool // write "label:" for missing values instead of leaving the field out
+}
+
+// LTSVOption configures LTSVWriter
+type LTSVOption func(opts *ltsvOptions)
+
+// WithLTSVEmptyFields writes every column in every record, missing values as "label:"
+func WithLTSVEmptyFields(p bool) LTSVOption {
+	return func(opts *ltsvOptions) {
+		opts.emptyFields = p
+	}
+}
+
+// LTSVWriter writes Labeled Tab-separated Values, one record per row of "label:value"
+// fields separated by tabs. the labels are the encoded headers, which LTSV limits to
+// [0-9A-Za-z_.-], e.g. those of NewHeaderCaseConv(SnakeCase, ...).
+// line breaks and tabs in values are written as \n, \r and \t, other control characters
+// as \x1b or \u009b and backslashes as \\
+type LTSVWriter struct {
+	w    *bufio.Writer
+	opts *ltsvOptions
+}
+
+// NewLTSVWriter returns new LTSVWriter
+func NewLTSVWriter(w io.Writer, options ...LTSVOption) *LTSVWriter {
+	opts := &ltsvOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &LTSVWriter{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// WriteLTSV writes the rows of results, the values are consumed like by CSVWriter.WriteCSV.
+// a header which is not a label, e.g. the path "/B2/0/B21", is an error
+func (w *LTSVWriter) WriteLTSV(results *KVs) error {
+	columns := results.Columns()
+	labels := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if !isLTSVLabel(c.Code) {
+			return fmt.Errorf("WriteLTSV: %q of %s is not a label of [0-9A-Za-z_.-]", c.Code, c.Path)
+		}
+		labels = append(labels, c.Code)
+	}
+
+	var b strings.Builder
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		b.Reset()
+		first := true
+		for i, v := range values {
+			if v == nil && !w.opts.emptyFields {
+				continue
+			}
+			if !first {
+				b.WriteByte('\t')
+			}
+			first = false
+			b.WriteString(labels[i])
+			b.WriteByte(':')
+			b.WriteString(escapeRecord(displayString(v)))
+		}
+		b.WriteByte('\n')
+		_, err := w.w.WriteString(b.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
+
+// isLTSVLabel reports whether s follows the label grammar of LTSV, [0-9A-Za-z_.-]+
+func isLTSVLabel(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || c == '_' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
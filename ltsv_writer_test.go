@@ -0,0 +1,61 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLTSVWriter_WriteLTSV(t *testing.T) {
+	type row struct {
+		Host   string
+		Status int
+		Agent  string
+	}
+	data := []row{{Host: "127.0.0.1", Status: 200, Agent: "curl\t\\7"}, {Host: "::1", Status: 404}}
+
+	tests := []struct {
+		name       string
+		headerConv HeaderConverter
+		opts       []LTSVOption
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "snake labels",
+			headerConv: NewHeaderCaseConv(SnakeCase, CollisionSuffix),
+			want: "agent:curl\\t\\\\7\thost:127.0.0.1\tstatus:200\n" +
+				"host:::1\tstatus:404\n",
+		},
+		{
+			name:       "empty fields",
+			headerConv: NewHeaderCaseConv(SnakeCase, CollisionSuffix),
+			opts:       []LTSVOption{WithLTSVEmptyFields(true)},
+			want: "agent:curl\\t\\\\7\thost:127.0.0.1\tstatus:200\n" +
+				"agent:\thost:::1\tstatus:404\n",
+		},
+		{
+			name:       "paths are not labels",
+			headerConv: NewHeaderOriginalStringConv(),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(tt.headerConv)
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			err = NewLTSVWriter(&b, tt.opts...).WriteLTSV(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteLTSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteLTSV() = \n%q\nwant\n%q", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package struct2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MappingFormat is the layout of a mapping file
+type MappingFormat int
+
+const (
+	MappingRows    MappingFormat = iota // two CSV rows, the paths then the codes, like CSVWriter.WriteMapping
+	MappingColumns                      // CSV with a "path,code,type" header row and one row per column
+	MappingJSON                         // JSON document holding KVs.Columns
+)
+
+const (
+	mappingCommentTitle = "# struct2csv mapping"
+	mappingCommentLine  = "# "
+	mappingJSONVersion  = 1
+)
+
+var mappingColumnsHeader = []string{"path", "code", "type"}
+
+// mappingDocument is the MappingJSON layout
+type mappingDocument struct {
+	Version int          `json:"version"`
+	Rows    int          `json:"rows"`
+	Columns []ColumnInfo `json:"columns"`
+}
+
+// WriteMappingAs writes the header mapping of kvs in format
+func WriteMappingAs(w io.Writer, kvs *KVs, format MappingFormat) error {
+	switch format {
+	case MappingRows:
+		return NewCSVWriter(w).WriteMapping(kvs)
+	case MappingColumns:
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(mappingColumnsRecords(kvs)); err != nil {
+			return err
+		}
+		return cw.Error()
+	case MappingJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(mappingDocument{
+			Version: mappingJSONVersion,
+			Rows:    kvs.Rows(),
+			Columns: kvs.Columns(),
+		})
+	default:
+		return fmt.Errorf("WriteMappingAs: unknown format %d", format)
+	}
+}
+
+func mappingColumnsRecords(kvs *KVs) [][]string {
+	columns := kvs.Columns()
+	records := make([][]string, 0, len(columns)+1)
+	records = append(records, mappingColumnsHeader)
+	for _, c := range columns {
+		records = append(records, []string{c.Path, c.Code, string(c.Type)})
+	}
+	return records
+}
+
+// WriteMappingComment writes the mapping of kvs in the MappingColumns layout as a block
+// of '#' comment lines, call it before WriteCSV to lead the data with its own mapping.
+// ReadMapping reads the block back and Mapping.DecodeCSV skips it. do not skip it with
+// csv.Reader.Comment, that also drops data rows whose first field starts with '#'
+func (w *CSVWriter) WriteMappingComment(kvs *KVs) error {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if err := cw.WriteAll(mappingColumnsRecords(kvs)); err != nil {
+		return err
+	}
+
+	w.begin()
+	w.bw.WriteString(mappingCommentTitle)
+	w.bw.WriteByte('\n')
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		w.bw.WriteString(mappingCommentLine)
+		w.bw.WriteString(line)
+	}
+	w.bw.WriteByte('\n')
+	return w.done()
+}
+
+// Mapping is the bidirectional table between paths and their encoded KeyType
+// read back from a mapping file
+type Mapping struct {
+	keys  map[string]KeyType   // path => key
+	paths map[string]string    // key's String() => path
+	types map[string]ValueType // path => type, empty for MappingRows
+}
+
+// ReadMapping reads a mapping in any MappingFormat, or the comment block
+// of CSVWriter.WriteMappingComment leading a data CSV.
+// codes that are unsigned integers become KeyAutoIncrementID, others KeyString
+func ReadMapping(r io.Reader) (*Mapping, error) {
+	m, err := readMapping(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("ReadMapping: %w", err)
+	}
+	return m, nil
+}
+
+func readMapping(br *bufio.Reader) (*Mapping, error) {
+	m := &Mapping{
+		keys:  make(map[string]KeyType),
+		paths: make(map[string]string),
+		types: make(map[string]ValueType),
+	}
+
+	skipBOM(br)
+	if head, _ := br.Peek(len(mappingCommentTitle)); string(head) == mappingCommentTitle {
+		return m, m.readComment(br)
+	}
+	if first, err := firstNonSpace(br); err == nil && first == '{' {
+		doc := mappingDocument{}
+		if err := json.NewDecoder(br).Decode(&doc); err != nil {
+			return nil, err
+		}
+		for _, c := range doc.Columns {
+			if err := m.add(c.Path, c.Code, c.Type); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	records, err := csv.NewReader(br).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return m, m.addRecords(records)
+}
+
+// readComment reads the comment block of CSVWriter.WriteMappingComment
+func (m *Mapping) readComment(br *bufio.Reader) error {
+	block, err := readCommentBlock(br)
+	if err != nil {
+		return err
+	}
+	records, err := csv.NewReader(strings.NewReader(block)).ReadAll()
+	if err != nil {
+		return err
+	}
+	return m.addRecords(records)
+}
+
+// readCommentBlock consumes the comment block of WriteMappingComment and returns the CSV
+// inside it, the line after the block is left unread
+func readCommentBlock(br *bufio.Reader) (string, error) {
+	var block strings.Builder
+	if _, err := br.ReadString('\n'); err != nil { // title
+		return "", err
+	}
+	for {
+		if head, _ := br.Peek(len(mappingCommentLine)); string(head) != mappingCommentLine {
+			break
+		}
+		line, err := br.ReadString('\n')
+		block.WriteString(strings.TrimPrefix(line, mappingCommentLine))
+		if err != nil {
+			break
+		}
+	}
+	return block.String(), nil
+}
+
+// addRecords adds the records of the MappingRows or MappingColumns layout
+func (m *Mapping) addRecords(records [][]string) error {
+	if len(records) > 0 && len(records[0]) == len(mappingColumnsHeader) &&
+		strings.Join(records[0], ",") == strings.Join(mappingColumnsHeader, ",") {
+		for _, record := range records[1:] {
+			if err := m.add(record[0], record[1], ValueType(record[2])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch len(records) {
+	case 0:
+		return nil
+	case 2:
+		for i, path := range records[0] {
+			if err := m.add(path, records[1][i], ValueTypeNone); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("mapping has %d rows, want 2", len(records))
+	}
+}
+
+func (m *Mapping) add(path, code string, valueType ValueType) error {
+	if other, ok := m.paths[code]; ok {
+		return fmt.Errorf("paths %s and %s share the code %s", other, path, code)
+	}
+
+	var key KeyType = newKeyString(code)
+	if id, err := strconv.ParseUint(code, 10, 64); err == nil {
+		key = KeyAutoIncrementID(id)
+	}
+	m.keys[path] = key
+	m.paths[code] = path
+	if valueType != ValueTypeNone {
+		m.types[path] = valueType
+	}
+	return nil
+}
+
+// skipBOM drops the UTF-8 byte order mark written by WithBOM
+func skipBOM(br *bufio.Reader) {
+	if head, _ := br.Peek(len(utf8BOM)); string(head) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+}
+
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return 0, err
+		}
+		switch c := b[i-1]; c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return c, nil
+		}
+	}
+}
+
+// Len returns the number of paths
+func (m *Mapping) Len() int {
+	return len(m.keys)
+}
+
+// Key returns the encoded key of path
+func (m *Mapping) Key(path string) (KeyType, bool) {
+	key, ok := m.keys[path]
+	return key, ok
+}
+
+// Path returns the path of an encoded header
+func (m *Mapping) Path(header string) (string, bool) {
+	path, ok := m.paths[header]
+	return path, ok
+}
+
+// Type returns the value type of path, ValueTypeNone if the mapping has no types
+func (m *Mapping) Type(path string) ValueType {
+	return m.types[path]
+}
+
+// Paths returns all paths sorted
+func (m *Mapping) Paths() []string {
+	paths := make([]string, 0, len(m.keys))
+	for path := range m.keys {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// DecodeHeaders returns the paths of an encoded header row,
+// it fails on a header missing from the mapping
+func (m *Mapping) DecodeHeaders(header []string) ([]string, error) {
+	paths := make([]string, 0, len(header))
+	for _, h := range header {
+		path, ok := m.paths[h]
+		if !ok {
+			return nil, fmt.Errorf("DecodeHeaders: unknown header %s", h)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// DecodeCSV copies the CSV of src to dst with its header row decoded to paths,
+// the data rows are streamed unchanged and a leading block of WriteMappingComment is dropped.
+// options are those src was written with: its delimiter is read and written, WithCRLF,
+// WithAlwaysQuote and WithBOM apply to dst, and with WithHeaderLevels the header rows,
+// which hold paths already, are checked against the mapping and written as one row
+func (m *Mapping) DecodeCSV(dst io.Writer, src io.Reader, options ...CSVOption) error {
+	br := bufio.NewReader(src)
+	skipBOM(br)
+	if head, _ := br.Peek(len(mappingCommentTitle)); string(head) == mappingCommentTitle {
+		if _, err := readCommentBlock(br); err != nil {
+			return err
+		}
+	}
+	w := NewCSVWriter(dst, options...)
+	r := csv.NewReader(br)
+	r.Comma = w.opts.comma
+
+	var paths []string
+	if w.opts.levels {
+		if _, err := br.Peek(1); err == io.EOF {
+			return nil
+		}
+		var err error
+		if paths, err = ReadHeaderLevels(r); err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if _, ok := m.keys[path]; !ok {
+				return fmt.Errorf("DecodeCSV: unknown path %s", path)
+			}
+		}
+	} else {
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if paths, err = m.DecodeHeaders(header); err != nil {
+			return err
+		}
+	}
+	if err := w.writeRecord(paths); err != nil {
+		return err
+	}
+
+	r.ReuseRecord = true // ReadHeaderLevels keeps its records
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := w.writeRecord(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
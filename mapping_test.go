@@ -0,0 +1,232 @@
+package struct2csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapping_DecodeCSV(t *testing.T) {
+	type row struct {
+		Name string
+		Tags []string
+	}
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+	kvs, err := conv.Convert([]row{{Name: "x, y", Tags: []string{"a"}}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var data, mapping strings.Builder
+	if err := NewCSVWriter(&mapping).WriteMapping(kvs); err != nil {
+		t.Fatalf("WriteMapping() error = %v", err)
+	}
+	if err := NewCSVWriter(&data).WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	m, err := ReadMapping(strings.NewReader(mapping.String()))
+	if err != nil {
+		t.Fatalf("ReadMapping() error = %v", err)
+	}
+	if paths := m.Paths(); !reflect.DeepEqual(paths, []string{"/Name", "/Tags/0"}) {
+		t.Errorf("Paths() = %v", paths)
+	}
+	if key, _ := m.Key("/Name"); key != kvs.GetMapping()["/Name"] {
+		t.Errorf("Key() = %v, want %v", key, kvs.GetMapping()["/Name"])
+	}
+
+	var decoded strings.Builder
+	if err := m.DecodeCSV(&decoded, strings.NewReader(data.String())); err != nil {
+		t.Fatalf("DecodeCSV() error = %v", err)
+	}
+	want := "/Name,/Tags/0\n\"x, y\",a\n"
+	if decoded.String() != want {
+		t.Errorf("DecodeCSV() = %q, want %q", decoded.String(), want)
+	}
+
+	if _, err := m.DecodeHeaders([]string{"9"}); err == nil {
+		t.Error("DecodeHeaders() error = nil, want unknown header error")
+	}
+}
+
+func TestMapping_DecodeCSVOptions(t *testing.T) {
+	type row struct {
+		Name string
+		Tags []string
+	}
+
+	tests := []struct {
+		name       string
+		writeOpts  []CSVOption
+		decodeOpts []CSVOption
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "delimiter",
+			writeOpts:  []CSVOption{WithDelimiter(';')},
+			decodeOpts: []CSVOption{WithDelimiter(';')},
+			want:       "/Name;/Tags/0\n\"x; y\";a\n",
+		},
+		{
+			name:       "delimiter and crlf",
+			writeOpts:  []CSVOption{WithDelimiter('\t')},
+			decodeOpts: []CSVOption{WithDelimiter('\t'), WithCRLF(true)},
+			want:       "/Name\t/Tags/0\r\nx; y\ta\r\n",
+		},
+		{
+			name:      "unknown delimiter",
+			writeOpts: []CSVOption{WithDelimiter(';')},
+			wantErr:   true,
+		},
+		{
+			name:       "header levels",
+			writeOpts:  []CSVOption{WithHeaderLevels(true)},
+			decodeOpts: []CSVOption{WithHeaderLevels(true)},
+			want:       "/Name,/Tags/0\nx; y,a\n",
+		},
+		{
+			name:      "unknown header levels",
+			writeOpts: []CSVOption{WithHeaderLevels(true)},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+			kvs, err := conv.Convert([]row{{Name: "x; y", Tags: []string{"a"}}})
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var data, mapping strings.Builder
+			if err := NewCSVWriter(&mapping).WriteMapping(kvs); err != nil {
+				t.Fatalf("WriteMapping() error = %v", err)
+			}
+			if err := NewCSVWriter(&data, tt.writeOpts...).WriteCSV(kvs); err != nil {
+				t.Fatalf("WriteCSV() error = %v", err)
+			}
+			m, err := ReadMapping(strings.NewReader(mapping.String()))
+			if err != nil {
+				t.Fatalf("ReadMapping() error = %v", err)
+			}
+
+			var decoded strings.Builder
+			err = m.DecodeCSV(&decoded, strings.NewReader(data.String()), tt.decodeOpts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && decoded.String() != tt.want {
+				t.Errorf("DecodeCSV() = %q, want %q", decoded.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMappingAs(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+	kvs, err := conv.Convert([]testStruct{{A1: 1, B1: []int{2}}, {A1: 3, A2: true}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	wantColumns := []ColumnInfo{
+		{Path: "/A1", Code: "1", Type: ValueTypeInt, FirstRow: 0, Filled: 2},
+		{Path: "/B1/0", Code: "2", Type: ValueTypeInt, FirstRow: 0, Filled: 1},
+		{Path: "/A2", Code: "3", Type: ValueTypeBool, FirstRow: 1, Filled: 1},
+	}
+	if columns := kvs.Columns(); !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("Columns() = %v, want %v", columns, wantColumns)
+	}
+
+	for _, format := range []MappingFormat{MappingRows, MappingColumns, MappingJSON} {
+		var b strings.Builder
+		if err := WriteMappingAs(&b, kvs, format); err != nil {
+			t.Fatalf("WriteMappingAs(%d) error = %v", format, err)
+		}
+
+		m, err := ReadMapping(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("ReadMapping(%d) error = %v", format, err)
+		}
+		if key, _ := m.Key("/A2"); key != KeyAutoIncrementID(3) {
+			t.Errorf("ReadMapping(%d) got /A2 = %v, want 3", format, key)
+		}
+		wantType := ValueTypeBool
+		if format == MappingRows {
+			wantType = ValueTypeNone
+		}
+		if got := m.Type("/A2"); got != wantType {
+			t.Errorf("ReadMapping(%d) got type %q, want %q", format, got, wantType)
+		}
+	}
+}
+
+func TestCSVWriter_WriteMappingComment(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+	kvs, err := conv.Convert([]testStruct{{A1: 1, B1: []int{2}}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	w := NewCSVWriter(&b)
+	if err := w.WriteMappingComment(kvs); err != nil {
+		t.Fatalf("WriteMappingComment() error = %v", err)
+	}
+	if err := w.WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "# struct2csv mapping\n# path,code,type\n# /A1,1,int\n# /B1/0,2,int\n1,2\n1,2\n"
+	if b.String() != want {
+		t.Fatalf("WriteMappingComment() = %q, want %q", b.String(), want)
+	}
+
+	m, err := ReadMapping(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadMapping() error = %v", err)
+	}
+	var decoded strings.Builder
+	if err := m.DecodeCSV(&decoded, strings.NewReader(b.String())); err != nil {
+		t.Fatalf("DecodeCSV() error = %v", err)
+	}
+	if decoded.String() != "/A1,/B1/0\n1,2\n" {
+		t.Errorf("DecodeCSV() = %q", decoded.String())
+	}
+}
+
+func TestMapping_DecodeCSVHashData(t *testing.T) {
+	type row struct {
+		Tag   string
+		Count int
+	}
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv(), WithInsertionOrder(true))
+	kvs, err := conv.Convert([]row{{Tag: "#hashtag", Count: 1}, {Tag: "# spaced", Count: 2}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	w := NewCSVWriter(&b)
+	if err := w.WriteMappingComment(kvs); err != nil {
+		t.Fatalf("WriteMappingComment() error = %v", err)
+	}
+	if err := w.WriteCSV(kvs); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	m, err := ReadMapping(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadMapping() error = %v", err)
+	}
+	var decoded strings.Builder
+	if err := m.DecodeCSV(&decoded, strings.NewReader(b.String())); err != nil {
+		t.Fatalf("DecodeCSV() error = %v", err)
+	}
+	if want := "/Tag,/Count\n#hashtag,1\n# spaced,2\n"; decoded.String() != want {
+		t.Errorf("DecodeCSV() = %q, want %q", decoded.String(), want)
+	}
+}
@@ -0,0 +1,77 @@
+package struct2csv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes the characters GitHub flavored markdown would format, newlines become <br>
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`,
+	"[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;", "&", "&amp;",
+	"\r\n", "<br>", "\n", "<br>", "\r", "<br>",
+)
+
+// MarkdownWriter writes a GitHub flavored markdown table, numeric columns are right aligned.
+// markdown tables have a single header row, WithTableGroupHeader does not apply
+type MarkdownWriter struct {
+	w    *bufio.Writer
+	opts *tableOptions
+	buf  []byte
+}
+
+// NewMarkdownWriter returns new MarkdownWriter
+func NewMarkdownWriter(w io.Writer, options ...TableOption) *MarkdownWriter {
+	return &MarkdownWriter{
+		w:    bufio.NewWriter(w),
+		opts: newTableOptions(options),
+		buf:  make([]byte, 0, 4096),
+	}
+}
+
+// WriteMarkdown writes the table of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *MarkdownWriter) WriteMarkdown(results *KVs) error {
+	columns := results.Columns()
+	if len(columns) == 0 {
+		return nil
+	}
+
+	w.buf = w.buf[:0]
+	for _, c := range columns {
+		w.buf = w.appendCell(w.buf, w.opts.label(c))
+	}
+	w.buf = append(w.buf, "|\n"...)
+	for _, c := range columns {
+		if isNumericType(c.Type) {
+			w.buf = append(w.buf, "| ---: "...)
+		} else {
+			w.buf = append(w.buf, "| --- "...)
+		}
+	}
+	w.buf = append(w.buf, "|\n"...)
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		w.buf = w.buf[:0]
+		for _, v := range values {
+			w.buf = w.appendCell(w.buf, displayString(v))
+		}
+		w.buf = append(w.buf, "|\n"...)
+		_, err := w.w.Write(w.buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
+
+func (w *MarkdownWriter) appendCell(b []byte, s string) []byte {
+	b = append(b, "| "...)
+	b = append(b, markdownEscaper.Replace(w.opts.truncate(s))...)
+	return append(b, ' ')
+}
@@ -0,0 +1,58 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownWriter_WriteMarkdown(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+		Score float64
+	}
+	data := []row{{Name: "a|b *c*", Count: 10, Score: 0.5}, {Name: "line\nbreak <x>"}}
+
+	tests := []struct {
+		name       string
+		headerConv HeaderConverter
+		opts       []TableOption
+		want       string
+	}{
+		{
+			name:       "original",
+			headerConv: NewHeaderOriginalStringConv(),
+			want: "| /Count | /Name | /Score |\n" +
+				"| ---: | --- | ---: |\n" +
+				"| 10 | a\\|b \\*c\\* | 0.5 |\n" +
+				"|  | line<br>break &lt;x&gt; |  |\n",
+		},
+		{
+			name:       "encoded with original header and truncation",
+			headerConv: NewHeaderAutoIncrementConv(),
+			opts:       []TableOption{WithTableOriginalHeader(true), WithTableMaxWidth(5)},
+			want: "| /Name | /Cou… | /Sco… |\n" +
+				"| --- | ---: | ---: |\n" +
+				"| a\\|b … | 10 | 0.5 |\n" +
+				"| line… |  |  |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(tt.headerConv)
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewMarkdownWriter(&b, tt.opts...).WriteMarkdown(kvs); err != nil {
+				t.Fatalf("WriteMarkdown() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteMarkdown() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package struct2csv
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// parquet physical types, encodings and other enums of parquet.thrift
+const (
+	parquetBoolean   int32 = 0
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+
+	parquetOptional int32 = 1
+
+	parquetConvertedUTF8            int32 = 0
+	parquetConvertedTimestampMicros int32 = 10
+	parquetConvertedUint64          int32 = 14
+
+	parquetPlain         int32 = 0
+	parquetRLE           int32 = 3
+	parquetRLEDictionary int32 = 8
+
+	parquetUncompressed int32 = 0
+
+	parquetDataPage       int32 = 0
+	parquetDictionaryPage int32 = 2
+)
+
+// thrift compact protocol types
+const (
+	thriftTrue   byte = 1
+	thriftFalse  byte = 2
+	thriftByte   byte = 3
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter encodes structs in the thrift compact protocol, which parquet uses for its metadata
+type thriftWriter struct {
+	b     []byte
+	last  int16   // id of the previous field of the current struct
+	stack []int16 // last of the enclosing structs
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.b = append(t.b, byte(delta)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.b = appendUvarint(t.b, zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.b = appendUvarint(t.b, zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.b = appendUvarint(t.b, zigzag(v))
+}
+
+func (t *thriftWriter) i8(id int16, v int8) {
+	t.field(id, thriftByte)
+	t.b = append(t.b, byte(v))
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.b = appendUvarint(t.b, uint64(len(s)))
+	t.b = append(t.b, s...)
+}
+
+// beginStruct starts the struct field id, end closes it
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.push()
+}
+
+// push starts a struct, either the top level one or an element of a list
+func (t *thriftWriter) push() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// end writes the stop field of the current struct
+func (t *thriftWriter) end() {
+	t.b = append(t.b, 0)
+	if n := len(t.stack); n > 0 {
+		t.last = t.stack[n-1]
+		t.stack = t.stack[:n-1]
+	}
+}
+
+// list starts the list field id of n elements, the caller appends them
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elem)
+	} else {
+		t.b = append(t.b, 0xf0|elem)
+		t.b = appendUvarint(t.b, uint64(n))
+	}
+}
+
+func (t *thriftWriter) listI32(id int16, vs []int32) {
+	t.list(id, thriftI32, len(vs))
+	for _, v := range vs {
+		t.b = appendUvarint(t.b, zigzag(int64(v)))
+	}
+}
+
+func (t *thriftWriter) listString(id int16, vs []string) {
+	t.list(id, thriftBinary, len(vs))
+	for _, v := range vs {
+		t.b = appendUvarint(t.b, uint64(len(v)))
+		t.b = append(t.b, v...)
+	}
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendHybrid appends values with the RLE / bit-packing hybrid encoding of parquet:
+// runs of at least 8 equal values are run length encoded, the rest is bit-packed in groups of 8
+func appendHybrid(b []byte, values []uint32, width int) []byte {
+	for i := 0; i < len(values); {
+		if n := runLength(values[i:]); n >= 8 {
+			b = appendUvarint(b, uint64(n)<<1)
+			for j := 0; j < (width+7)/8; j++ {
+				b = append(b, byte(values[i]>>(8*j)))
+			}
+			i += n
+			continue
+		}
+
+		// bit-pack groups up to the next long run, the last group is padded with zeros
+		start := i
+		for i < len(values) && runLength(values[i:]) < 8 {
+			i += 8
+		}
+		if i > len(values) {
+			i = len(values)
+		}
+		groups := (i - start + 7) / 8
+		b = appendUvarint(b, uint64(groups)<<1|1)
+		b = appendBitPacked(b, values[start:i], groups*8, width)
+	}
+	return b
+}
+
+func runLength(values []uint32) int {
+	n := 1
+	for n < len(values) && values[n] == values[0] {
+		n++
+	}
+	return n
+}
+
+// appendBitPacked packs count values of width bits, least significant bit first,
+// values beyond len(values) are zero
+func appendBitPacked(b []byte, values []uint32, count, width int) []byte {
+	var acc uint64
+	var n int
+	for i := 0; i < count; i++ {
+		var v uint32
+		if i < len(values) {
+			v = values[i]
+		}
+		acc |= uint64(v) << n
+		n += width
+		for n >= 8 {
+			b = append(b, byte(acc))
+			acc >>= 8
+			n -= 8
+		}
+	}
+	if n > 0 {
+		b = append(b, byte(acc))
+	}
+	return b
+}
+
+// bitWidth returns the bits needed for values up to max, at least 1
+func bitWidth(max int) int {
+	if w := bits.Len(uint(max)); w > 0 {
+		return w
+	}
+	return 1
+}
+
+// appendPlain appends v with the PLAIN encoding of the physical type
+func appendPlain(b []byte, physical int32, v interface{}) []byte {
+	var buf [8]byte
+	switch physical {
+	case parquetInt64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.(int64)))
+		return append(b, buf[:]...)
+	case parquetDouble:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v.(float64)))
+		return append(b, buf[:]...)
+	default: // parquetByteArray
+		s := v.(string)
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(s)))
+		b = append(b, buf[:4]...)
+		return append(b, s...)
+	}
+}
+
+// appendPlainBools appends bools with the PLAIN encoding, bit-packed least significant bit first
+func appendPlainBools(b []byte, values []interface{}) []byte {
+	bools := make([]uint32, 0, len(values))
+	for _, v := range values {
+		if v == true {
+			bools = append(bools, 1)
+		} else {
+			bools = append(bools, 0)
+		}
+	}
+	return appendBitPacked(b, bools, len(bools), 1)
+}
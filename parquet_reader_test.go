@@ -0,0 +1,315 @@
+package struct2csv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// a minimal parquet reader for the tests, it decodes what ParquetWriter writes:
+// uncompressed v1 data pages with PLAIN or RLE_DICTIONARY values of flat optional columns
+
+// thriftReader decodes the thrift compact protocol into generic values:
+// structs become map[int16]interface{}, lists []interface{}, integers int64,
+// binaries []byte and bools bool
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errors.New("thrift: unexpected end")
+	}
+	c := r.b[r.pos]
+	r.pos++
+	return c, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, errors.New("thrift: bad varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	u, err := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+func (r *thriftReader) readStruct() (map[int16]interface{}, error) {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		head, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if head == 0 {
+			return fields, nil
+		}
+		typ := head & 0x0f
+		id := last + int16(head>>4)
+		if head>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+
+		switch typ {
+		case thriftTrue:
+			fields[id] = true
+		case thriftFalse:
+			fields[id] = false
+		default:
+			if fields[id], err = r.readValue(typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftByte:
+		c, err := r.byte()
+		return int64(int8(c)), err
+	case 4, thriftI32, thriftI64: // 4 is i16
+		return r.varint()
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil || r.pos+int(n) > len(r.b) {
+			return nil, errors.New("thrift: bad binary")
+		}
+		b := r.b[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return b, nil
+	case thriftList:
+		head, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := int(head >> 4)
+		if n == 15 {
+			size, err := r.uvarint()
+			if err != nil {
+				return nil, err
+			}
+			n = int(size)
+		}
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := r.readValue(head & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case thriftStruct:
+		return r.readStruct()
+	default:
+		return nil, fmt.Errorf("thrift: type %d", typ)
+	}
+}
+
+// parquetTestColumn is a column read back by readParquetTest
+type parquetTestColumn struct {
+	name      string
+	physical  int64
+	converted int64 // -1 if the schema element has none
+	logical   map[int16]interface{}
+	values    []interface{} // nil for nulls, int64, float64, bool or string
+	encodings map[int64]bool
+	rowGroups int
+}
+
+// readParquetTest reads every column of file, in schema order
+func readParquetTest(file []byte) ([]*parquetTestColumn, int64, error) {
+	n := len(file)
+	if n < 12 || string(file[:4]) != parquetMagic || string(file[n-4:]) != parquetMagic {
+		return nil, 0, errors.New("no PAR1 magic")
+	}
+	size := int(binary.LittleEndian.Uint32(file[n-8:]))
+	if size > n-12 {
+		return nil, 0, errors.New("bad footer size")
+	}
+	meta, err := (&thriftReader{b: file[n-8-size : n-8]}).readStruct()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	schema := meta[2].([]interface{})
+	columns := make([]*parquetTestColumn, 0, len(schema)-1)
+	for _, e := range schema[1:] {
+		element := e.(map[int16]interface{})
+		c := &parquetTestColumn{
+			name:      string(element[4].([]byte)),
+			physical:  element[1].(int64),
+			converted: -1,
+			encodings: map[int64]bool{},
+		}
+		if v, ok := element[6]; ok {
+			c.converted = v.(int64)
+		}
+		if v, ok := element[10]; ok {
+			c.logical = v.(map[int16]interface{})
+		}
+		columns = append(columns, c)
+	}
+
+	for _, g := range meta[4].([]interface{}) {
+		chunks := g.(map[int16]interface{})[1].([]interface{})
+		for i, ch := range chunks {
+			md := ch.(map[int16]interface{})[3].(map[int16]interface{})
+			c := columns[i]
+			c.rowGroups++
+			for _, e := range md[2].([]interface{}) {
+				c.encodings[e.(int64)] = true
+			}
+			if err := c.readChunk(file, md); err != nil {
+				return nil, 0, fmt.Errorf("column %s: %w", c.name, err)
+			}
+		}
+	}
+	return columns, meta[3].(int64), nil
+}
+
+func (c *parquetTestColumn) readChunk(file []byte, md map[int16]interface{}) error {
+	var dict []interface{}
+	if off, ok := md[11]; ok {
+		header, body, err := readParquetTestPage(file, off.(int64))
+		if err != nil {
+			return err
+		}
+		count := int(header[7].(map[int16]interface{})[1].(int64))
+		if dict, _, err = c.plainValues(body, count); err != nil {
+			return err
+		}
+	}
+
+	header, body, err := readParquetTestPage(file, md[9].(int64))
+	if err != nil {
+		return err
+	}
+	page := header[5].(map[int16]interface{})
+	count := int(page[1].(int64))
+
+	levelSize := int(binary.LittleEndian.Uint32(body))
+	levels, err := readHybrid(body[4:4+levelSize], 1, count)
+	if err != nil {
+		return err
+	}
+	body = body[4+levelSize:]
+	present := 0
+	for _, l := range levels {
+		present += int(l)
+	}
+
+	var values []interface{}
+	switch page[2].(int64) {
+	case int64(parquetPlain):
+		values, _, err = c.plainValues(body, present)
+	case int64(parquetRLEDictionary):
+		var indexes []uint32
+		if indexes, err = readHybrid(body[1:], int(body[0]), present); err == nil {
+			for _, i := range indexes {
+				values = append(values, dict[i])
+			}
+		}
+	default:
+		err = fmt.Errorf("encoding %d", page[2])
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, l := range levels {
+		if l == 0 {
+			c.values = append(c.values, nil)
+			continue
+		}
+		c.values = append(c.values, values[0])
+		values = values[1:]
+	}
+	return nil
+}
+
+func readParquetTestPage(file []byte, offset int64) (map[int16]interface{}, []byte, error) {
+	r := &thriftReader{b: file, pos: int(offset)}
+	header, err := r.readStruct()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := int(header[3].(int64))
+	return header, file[r.pos : r.pos+size], nil
+}
+
+func (c *parquetTestColumn) plainValues(b []byte, count int) ([]interface{}, []byte, error) {
+	values := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		switch int32(c.physical) {
+		case parquetBoolean:
+			values = append(values, b[i/8]>>(i%8)&1 == 1)
+		case parquetInt64:
+			values = append(values, int64(binary.LittleEndian.Uint64(b)))
+			b = b[8:]
+		case parquetDouble:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+			b = b[8:]
+		case parquetByteArray:
+			n := int(binary.LittleEndian.Uint32(b))
+			values = append(values, string(b[4:4+n]))
+			b = b[4+n:]
+		default:
+			return nil, nil, fmt.Errorf("physical type %d", c.physical)
+		}
+	}
+	return values, b, nil
+}
+
+// readHybrid decodes count values of the RLE / bit-packed hybrid encoding
+func readHybrid(b []byte, width, count int) ([]uint32, error) {
+	values := make([]uint32, 0, count)
+	r := &thriftReader{b: b}
+	for len(values) < count {
+		head, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if head&1 == 0 { // run
+			var v uint32
+			for i := 0; i < (width+7)/8; i++ {
+				c, err := r.byte()
+				if err != nil {
+					return nil, err
+				}
+				v |= uint32(c) << (8 * i)
+			}
+			for i := 0; i < int(head>>1); i++ {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		n := int(head>>1) * 8
+		packed := b[r.pos : r.pos+n*width/8]
+		r.pos += n * width / 8
+		for i := 0; i < n; i++ {
+			var v uint32
+			for bit := 0; bit < width; bit++ {
+				at := i*width + bit
+				v |= uint32(packed[at/8]>>(at%8)&1) << bit
+			}
+			values = append(values, v)
+		}
+	}
+	return values[:count], nil
+}
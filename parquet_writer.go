@@ -0,0 +1,465 @@
+package struct2csv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+const parquetMagic = "PAR1"
+
+type parquetOptions struct {
+	rowGroupSize    int  // rows per row group
+	dictionaryLimit int  // most distinct values of a dictionary encoded column chunk, 0 disables it
+	originalNames   bool // name the columns by path instead of encoded header
+}
+
+type ParquetOption func(opts *parquetOptions)
+
+// WithParquetRowGroupSize sets the rows per row group, the default is 65536.
+// the rows of one group are held in memory until it is written
+func WithParquetRowGroupSize(rows int) ParquetOption {
+	return func(opts *parquetOptions) {
+		opts.rowGroupSize = rows
+	}
+}
+
+// WithParquetDictionaryLimit dictionary encodes the column chunks holding at most n distinct
+// values, and at most half as many as values, the default is 1024. 0 disables dictionaries
+func WithParquetDictionaryLimit(n int) ParquetOption {
+	return func(opts *parquetOptions) {
+		opts.dictionaryLimit = n
+	}
+}
+
+// WithParquetOriginalNames names the columns by path, e.g. "/B2/0/B21", instead of the encoded header
+func WithParquetOriginalNames(p bool) ParquetOption {
+	return func(opts *parquetOptions) {
+		opts.originalNames = p
+	}
+}
+
+// ParquetWriter writes an Apache Parquet file with one optional column per header.
+// the column types follow the observed value types: int and uint become INT64,
+// float DOUBLE, number INT64 if every value is an integer of int64 range and
+// DOUBLE otherwise, bool BOOLEAN, time a TIMESTAMP of microseconds and
+// the rest UTF8 strings
+type ParquetWriter struct {
+	w    *countWriter
+	opts *parquetOptions
+}
+
+// NewParquetWriter returns new ParquetWriter
+func NewParquetWriter(w io.Writer, options ...ParquetOption) *ParquetWriter {
+	opts := &parquetOptions{
+		rowGroupSize:    65536,
+		dictionaryLimit: 1024,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &ParquetWriter{
+		w:    &countWriter{w: bufio.NewWriter(w)},
+		opts: opts,
+	}
+}
+
+type parquetColumn struct {
+	name      string
+	valueType ValueType
+	physical  int32
+	values    []interface{} // values of the current row group, nil for missing values
+}
+
+type parquetChunk struct {
+	column           *parquetColumn
+	encodings        []int32
+	values           int
+	offset           int64 // offset of the first page
+	dictionaryOffset int64 // offset of the dictionary page, -1 if there is none
+	dataOffset       int64
+	size             int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int
+	size   int64
+}
+
+// WriteParquet writes the file of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *ParquetWriter) WriteParquet(results *KVs) error {
+	if w.opts.rowGroupSize <= 0 {
+		return fmt.Errorf("WriteParquet: row group size %d", w.opts.rowGroupSize)
+	}
+
+	columns := make([]*parquetColumn, 0, len(results.mapping))
+	for _, c := range results.Columns() {
+		name := c.Code
+		if w.opts.originalNames {
+			name = c.Path
+		}
+		valueType := c.Type
+		if valueType == ValueTypeNumber && results.integerColumn(c.Path) {
+			valueType = ValueTypeInt // exact, a DOUBLE would round IDs beyond 2^53
+		}
+		columns = append(columns, &parquetColumn{
+			name:      name,
+			valueType: valueType,
+			physical:  parquetPhysicalType(valueType),
+			values:    make([]interface{}, 0, w.opts.rowGroupSize),
+		})
+	}
+
+	if _, err := io.WriteString(w.w, parquetMagic); err != nil {
+		return fmt.Errorf("WriteParquet: %w", err)
+	}
+
+	var groups []parquetRowGroup
+	rows := 0
+	flush := func() error {
+		if rows == 0 {
+			return nil
+		}
+		group, err := w.writeRowGroup(columns, rows)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, group)
+		rows = 0
+		return nil
+	}
+
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		for i, v := range values {
+			columns[i].values = append(columns[i].values, parquetValue(columns[i].valueType, v))
+		}
+		rows++
+		if rows == w.opts.rowGroupSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		err = w.writeFooter(columns, groups)
+	}
+	if err != nil {
+		return fmt.Errorf("WriteParquet: %w", err)
+	}
+	return nil
+}
+
+func (w *ParquetWriter) writeRowGroup(columns []*parquetColumn, rows int) (parquetRowGroup, error) {
+	group := parquetRowGroup{rows: rows, chunks: make([]parquetChunk, 0, len(columns))}
+	for _, column := range columns {
+		chunk, err := w.writeColumnChunk(column)
+		if err != nil {
+			return group, err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+		column.values = column.values[:0]
+	}
+	return group, nil
+}
+
+func (w *ParquetWriter) writeColumnChunk(column *parquetColumn) (parquetChunk, error) {
+	chunk := parquetChunk{
+		column:           column,
+		values:           len(column.values),
+		offset:           w.w.n,
+		dictionaryOffset: -1,
+	}
+
+	levels := make([]uint32, len(column.values))
+	for i, v := range column.values {
+		if v != nil {
+			levels[i] = 1
+		}
+	}
+	page := appendHybrid(make([]byte, 4), levels, 1)
+	binary.LittleEndian.PutUint32(page, uint32(len(page)-4))
+
+	encoding := parquetPlain
+	if dict, indexes, ok := w.dictionary(column); ok {
+		var dictPage []byte
+		for _, v := range dict {
+			dictPage = appendPlain(dictPage, column.physical, v)
+		}
+		chunk.dictionaryOffset = w.w.n
+		if err := w.writePage(parquetDictionaryPage, dictPage, len(dict), parquetPlain); err != nil {
+			return chunk, err
+		}
+
+		width := bitWidth(len(dict) - 1)
+		page = append(page, byte(width))
+		page = appendHybrid(page, indexes, width)
+		encoding = parquetRLEDictionary
+		chunk.encodings = []int32{parquetPlain, parquetRLE, parquetRLEDictionary}
+	} else {
+		if column.physical == parquetBoolean {
+			page = appendPlainBools(page, nonNull(column.values))
+		} else {
+			for _, v := range column.values {
+				if v != nil {
+					page = appendPlain(page, column.physical, v)
+				}
+			}
+		}
+		chunk.encodings = []int32{parquetPlain, parquetRLE}
+	}
+
+	chunk.dataOffset = w.w.n
+	if err := w.writePage(parquetDataPage, page, len(column.values), encoding); err != nil {
+		return chunk, err
+	}
+	chunk.size = w.w.n - chunk.offset
+	return chunk, nil
+}
+
+// dictionary returns the distinct values of column and the index of every value,
+// false if the column should not be dictionary encoded
+func (w *ParquetWriter) dictionary(column *parquetColumn) ([]interface{}, []uint32, bool) {
+	if w.opts.dictionaryLimit <= 0 || column.physical == parquetBoolean {
+		return nil, nil, false
+	}
+
+	var dict []interface{}
+	seen := make(map[interface{}]uint32)
+	indexes := make([]uint32, 0, len(column.values))
+	for _, v := range column.values {
+		if v == nil {
+			continue
+		}
+		index, ok := seen[v]
+		if !ok {
+			if len(dict) == w.opts.dictionaryLimit {
+				return nil, nil, false
+			}
+			index = uint32(len(dict))
+			seen[v] = index
+			dict = append(dict, v)
+		}
+		indexes = append(indexes, index)
+	}
+	if len(dict) == 0 || len(dict)*2 > len(indexes) {
+		return nil, nil, false
+	}
+	return dict, indexes, true
+}
+
+func (w *ParquetWriter) writePage(pageType int32, body []byte, values int, encoding int32) error {
+	var t thriftWriter
+	t.push()
+	t.i32(1, pageType)
+	t.i32(2, int32(len(body)))
+	t.i32(3, int32(len(body)))
+	if pageType == parquetDictionaryPage {
+		t.beginStruct(7)
+		t.i32(1, int32(values))
+		t.i32(2, encoding)
+		t.end()
+	} else {
+		t.beginStruct(5)
+		t.i32(1, int32(values))
+		t.i32(2, encoding)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.end()
+	}
+	t.end()
+
+	if _, err := w.w.Write(t.b); err != nil {
+		return err
+	}
+	_, err := w.w.Write(body)
+	return err
+}
+
+func (w *ParquetWriter) writeFooter(columns []*parquetColumn, groups []parquetRowGroup) error {
+	var t thriftWriter
+	t.push()
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(columns)+1)
+	t.push()
+	t.string(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.end()
+	for _, column := range columns {
+		t.push()
+		appendParquetSchema(&t, column)
+		t.end()
+	}
+
+	rows := 0
+	for _, group := range groups {
+		rows += group.rows
+	}
+	t.i64(3, int64(rows))
+
+	t.list(4, thriftStruct, len(groups))
+	for _, group := range groups {
+		t.push()
+		t.list(1, thriftStruct, len(group.chunks))
+		for _, chunk := range group.chunks {
+			t.push()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, chunk.column.physical)
+			t.listI32(2, chunk.encodings)
+			t.listString(3, []string{chunk.column.name})
+			t.i32(4, parquetUncompressed)
+			t.i64(5, int64(chunk.values))
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.dataOffset)
+			if chunk.dictionaryOffset >= 0 {
+				t.i64(11, chunk.dictionaryOffset)
+			}
+			t.end()
+			t.end()
+		}
+		t.i64(2, group.size)
+		t.i64(3, int64(group.rows))
+		t.end()
+	}
+
+	t.string(6, "struct2csv")
+	t.end()
+
+	if _, err := w.w.Write(t.b); err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(t.b)))
+	if _, err := w.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, parquetMagic); err != nil {
+		return err
+	}
+	return w.w.w.Flush()
+}
+
+// appendParquetSchema appends the fields of the SchemaElement of column
+func appendParquetSchema(t *thriftWriter, column *parquetColumn) {
+	t.i32(1, column.physical)
+	t.i32(3, parquetOptional)
+	t.string(4, column.name)
+
+	switch column.valueType {
+	case ValueTypeUint:
+		t.i32(6, parquetConvertedUint64)
+		t.beginStruct(10)
+		t.beginStruct(10) // INTEGER
+		t.i8(1, 64)
+		t.bool(2, false)
+		t.end()
+		t.end()
+	case ValueTypeTime:
+		t.i32(6, parquetConvertedTimestampMicros)
+		t.beginStruct(10)
+		t.beginStruct(8) // TIMESTAMP
+		t.bool(1, true)
+		t.beginStruct(2)
+		t.beginStruct(2) // MICROS
+		t.end()
+		t.end()
+		t.end()
+		t.end()
+	case ValueTypeInt, ValueTypeFloat, ValueTypeNumber, ValueTypeBool:
+	default:
+		t.i32(6, parquetConvertedUTF8)
+		t.beginStruct(10)
+		t.beginStruct(1) // STRING
+		t.end()
+		t.end()
+	}
+}
+
+func parquetPhysicalType(t ValueType) int32 {
+	switch t {
+	case ValueTypeInt, ValueTypeUint, ValueTypeTime:
+		return parquetInt64
+	case ValueTypeFloat, ValueTypeNumber:
+		return parquetDouble
+	case ValueTypeBool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// parquetValue converts v to the Go type appendPlain expects for the physical type of t
+func parquetValue(t ValueType, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch t {
+	case ValueTypeInt, ValueTypeUint:
+		if n, ok := v.(json.Number); ok {
+			i, _ := n.Int64()
+			return i
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(rv.Uint())
+		}
+	case ValueTypeFloat, ValueTypeNumber:
+		if n, ok := v.(json.Number); ok {
+			f, _ := n.Float64()
+			return f
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return rv.Float()
+		}
+	case ValueTypeBool:
+		return v
+	case ValueTypeTime:
+		if tm, ok := v.(time.Time); ok {
+			return tm.Unix()*1e6 + int64(tm.Nanosecond())/1e3
+		}
+	}
+	return toString(v)
+}
+
+func nonNull(values []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if v != nil {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// countWriter counts the bytes written, parquet metadata refers to pages by offset
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
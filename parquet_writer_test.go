@@ -0,0 +1,295 @@
+package struct2csv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParquetWriter_WriteParquet(t *testing.T) {
+	type row struct {
+		ID    int64
+		Name  string
+		Ok    bool
+		Score float64
+	}
+	data := make([]row, 0, 10)
+	for i := 0; i < 10; i++ {
+		data = append(data, row{ID: int64(i + 1), Name: []string{"a", "b"}[i%2], Ok: i%2 == 0, Score: float64(i) / 2})
+	}
+
+	tests := []struct {
+		name    string
+		opts    []ParquetOption
+		names   []string
+		wantErr bool
+	}{
+		{name: "encoded", opts: []ParquetOption{WithParquetRowGroupSize(4)}, names: []string{"1", "2", "3", "4"}},
+		{name: "original names", opts: []ParquetOption{WithParquetOriginalNames(true), WithParquetDictionaryLimit(0)}, names: []string{"/ID", "/Name", "/Ok", "/Score"}},
+		{name: "no rows per group", opts: []ParquetOption{WithParquetRowGroupSize(0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderAutoIncrementConv(), WithResultCap(len(data)))
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b bytes.Buffer
+			err = NewParquetWriter(&b, tt.opts...).WriteParquet(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteParquet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			file := b.Bytes()
+			if !bytes.HasPrefix(file, []byte(parquetMagic)) || !bytes.HasSuffix(file, []byte(parquetMagic)) {
+				t.Fatalf("WriteParquet() misses the PAR1 magic")
+			}
+			size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+			if size <= 0 || size > len(file)-12 {
+				t.Fatalf("WriteParquet() footer size = %d of %d bytes", size, len(file))
+			}
+			footer := string(file[len(file)-8-size : len(file)-8])
+			for _, name := range tt.names {
+				if !strings.Contains(footer, name) {
+					t.Errorf("WriteParquet() footer misses column %q", name)
+				}
+			}
+			if !strings.HasSuffix(footer, "struct2csv\x00") {
+				t.Errorf("WriteParquet() footer = %q, want created_by struct2csv", footer)
+			}
+		})
+	}
+}
+
+func TestParquetWriter_dictionary(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int
+		column      parquetColumn
+		wantDict    []interface{}
+		wantIndexes []uint32
+	}{
+		{
+			name:        "low cardinality",
+			limit:       10,
+			column:      parquetColumn{physical: parquetByteArray, values: []interface{}{"a", nil, "b", "a", "a"}},
+			wantDict:    []interface{}{"a", "b"},
+			wantIndexes: []uint32{0, 1, 0, 0},
+		},
+		{
+			name:   "too many distinct values",
+			limit:  10,
+			column: parquetColumn{physical: parquetInt64, values: []interface{}{int64(1), int64(2), int64(3)}},
+		},
+		{
+			name:   "over the limit",
+			limit:  1,
+			column: parquetColumn{physical: parquetByteArray, values: []interface{}{"a", "b", "a", "b"}},
+		},
+		{
+			name:   "bool",
+			limit:  10,
+			column: parquetColumn{physical: parquetBoolean, values: []interface{}{true, true, true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewParquetWriter(&bytes.Buffer{}, WithParquetDictionaryLimit(tt.limit))
+			dict, indexes, ok := w.dictionary(&tt.column)
+			if ok != (tt.wantDict != nil) {
+				t.Fatalf("dictionary() ok = %v, want %v", ok, tt.wantDict != nil)
+			}
+			if !reflect.DeepEqual(dict, tt.wantDict) || !reflect.DeepEqual(indexes, tt.wantIndexes) {
+				t.Errorf("dictionary() = %v, %v, want %v, %v", dict, indexes, tt.wantDict, tt.wantIndexes)
+			}
+		})
+	}
+}
+
+func Test_appendHybrid(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []uint32
+		width  int
+		want   []byte
+	}{
+		{name: "run", values: []uint32{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, width: 1, want: []byte{10 << 1, 1}},
+		{name: "bit-packed", values: []uint32{0, 1, 0, 1}, width: 1, want: []byte{1<<1 | 1, 0b1010}},
+		{name: "wide run", values: []uint32{300, 300, 300, 300, 300, 300, 300, 300}, width: 9, want: []byte{8 << 1, 0x2c, 0x01}},
+		{
+			name:   "bit-packed then run",
+			values: []uint32{1, 2, 3, 0, 1, 2, 3, 0, 2, 2, 2, 2, 2, 2, 2, 2},
+			width:  2,
+			want:   []byte{1<<1 | 1, 0b00111001, 0b00111001, 8 << 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendHybrid(nil, tt.values, tt.width); !bytes.Equal(got, tt.want) {
+				t.Errorf("appendHybrid() = %08b, want %08b", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_thriftWriter(t *testing.T) {
+	var tw thriftWriter
+	tw.push()
+	tw.i32(1, 3)
+	tw.string(4, "ab")
+	tw.beginStruct(20)
+	tw.bool(1, true)
+	tw.end()
+	tw.listI32(21, []int32{0, -1})
+	tw.end()
+
+	want := []byte{
+		0x15, 0x06, // field 1 i32 zigzag(3)
+		0x38, 0x02, 'a', 'b', // field 4 binary
+		0x0c, 0x28, // field 20 struct, long form zigzag(20)
+		0x11, 0x00, // field 1 true, stop
+		0x19, 0x25, 0x00, 0x01, // field 21 list of 2 i32
+		0x00,
+	}
+	if !bytes.Equal(tw.b, want) {
+		t.Errorf("thriftWriter = % x, want % x", tw.b, want)
+	}
+}
+
+func TestParquetWriter_ReadBack(t *testing.T) {
+	type row struct {
+		ID    uint64
+		Name  string
+		At    time.Time
+		Score *float64
+		Ok    bool
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	score := 1.5
+	structs := []row{
+		{ID: math.MaxUint64, Name: "a", At: at, Score: &score, Ok: true},
+		{ID: 2, Name: "a", At: at.Add(time.Second), Score: &score},
+		{ID: 3, Name: "b", At: at.Add(time.Microsecond), Ok: true},
+	}
+	const input = `[
+		{"id": 1234567890123456789, "ratio": 0.5, "tag": "x"},
+		{"id": -7, "ratio": 2, "tag": null},
+		{"id": 9, "ratio": 1e3}
+	]`
+
+	micros := at.UnixNano() / 1e3
+	tests := []struct {
+		name       string
+		convert    func(*StructConverter) (*KVs, error)
+		opts       []ParquetOption
+		rows       int64
+		want       map[string][]interface{}
+		physical   map[string]int32
+		converted  map[string]int32
+		dictionary map[string]bool
+	}{
+		{
+			name:    "struct",
+			convert: func(conv *StructConverter) (*KVs, error) { return conv.Convert(structs) },
+			opts:    []ParquetOption{WithParquetOriginalNames(true), WithParquetRowGroupSize(2)},
+			rows:    3,
+			want: map[string][]interface{}{
+				"/ID":    {int64(-1), int64(2), int64(3)},
+				"/Name":  {"a", "a", "b"},
+				"/At":    {micros, micros + 1e6, micros + 1},
+				"/Score": {1.5, 1.5, nil},
+				"/Ok":    {true, nil, true}, // false is a zero value and left empty
+			},
+			physical: map[string]int32{
+				"/ID": parquetInt64, "/Name": parquetByteArray, "/At": parquetInt64,
+				"/Score": parquetDouble, "/Ok": parquetBoolean,
+			},
+			converted: map[string]int32{
+				"/ID": parquetConvertedUint64, "/Name": parquetConvertedUTF8, "/At": parquetConvertedTimestampMicros,
+				"/Score": -1, "/Ok": -1,
+			},
+			dictionary: map[string]bool{"/Name": true, "/Score": true, "/Ok": false},
+		},
+		{
+			name:    "json numbers",
+			convert: func(conv *StructConverter) (*KVs, error) { return conv.ConvertJSON(strings.NewReader(input)) },
+			opts:    []ParquetOption{WithParquetOriginalNames(true), WithParquetDictionaryLimit(0)},
+			rows:    3,
+			want: map[string][]interface{}{
+				"/id":    {int64(1234567890123456789), int64(-7), int64(9)},
+				"/ratio": {0.5, 2.0, 1000.0},
+				"/tag":   {"x", nil, nil},
+			},
+			physical:   map[string]int32{"/id": parquetInt64, "/ratio": parquetDouble, "/tag": parquetByteArray},
+			converted:  map[string]int32{"/id": -1, "/ratio": -1, "/tag": parquetConvertedUTF8},
+			dictionary: map[string]bool{"/id": false, "/tag": false},
+		},
+		{
+			name: "int and uint beyond int64",
+			convert: func(conv *StructConverter) (*KVs, error) {
+				return conv.Convert([]map[string]interface{}{
+					{"n": int64(-1), "u": uint64(1)},
+					{"n": uint64(math.MaxUint64), "u": int64(-2)},
+				})
+			},
+			opts: []ParquetOption{WithParquetOriginalNames(true), WithParquetDictionaryLimit(0)},
+			rows: 2,
+			want: map[string][]interface{}{
+				"/n": {-1.0, float64(math.MaxUint64)},
+				"/u": {int64(1), int64(-2)},
+			},
+			physical:  map[string]int32{"/n": parquetDouble, "/u": parquetInt64},
+			converted: map[string]int32{"/n": -1, "/u": -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+			kvs, err := tt.convert(conv)
+			if err != nil {
+				t.Fatalf("convert error = %v", err)
+			}
+			var b bytes.Buffer
+			if err := NewParquetWriter(&b, tt.opts...).WriteParquet(kvs); err != nil {
+				t.Fatalf("WriteParquet() error = %v", err)
+			}
+
+			columns, rows, err := readParquetTest(b.Bytes())
+			if err != nil {
+				t.Fatalf("readParquetTest() error = %v", err)
+			}
+			if rows != tt.rows {
+				t.Errorf("num_rows = %d, want %d", rows, tt.rows)
+			}
+			if len(columns) != len(tt.want) {
+				t.Fatalf("read %d columns, want %d", len(columns), len(tt.want))
+			}
+			for _, c := range columns {
+				if want, ok := tt.want[c.name]; !ok || !reflect.DeepEqual(c.values, want) {
+					t.Errorf("column %s = %v, want %v", c.name, c.values, want)
+				}
+				if c.physical != int64(tt.physical[c.name]) {
+					t.Errorf("column %s type = %d, want %d", c.name, c.physical, tt.physical[c.name])
+				}
+				if c.converted != int64(tt.converted[c.name]) {
+					t.Errorf("column %s converted type = %d, want %d", c.name, c.converted, tt.converted[c.name])
+				}
+				if want, ok := tt.dictionary[c.name]; ok && c.encodings[int64(parquetRLEDictionary)] != want {
+					t.Errorf("column %s dictionary encoded = %v, want %v", c.name, !want, want)
+				}
+			}
+		})
+	}
+}
@@ -45,6 +45,11 @@ func (p PathBuilder) Clone(growSize int) PathBuilder {
 	return PathBuilder{b}
 }
 
+// path returns full path string without resetting the PathBuilder.
+func (p PathBuilder) path() string {
+	return p.b.String()
+}
+
 // String returns full path string.
 func (p PathBuilder) String() string {
 	s := p.b.String()
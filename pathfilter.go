@@ -0,0 +1,186 @@
+package struct2csv
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// recursiveToken matches zero or more path segments in include and exclude patterns
+const recursiveToken = "**"
+
+// pathPattern is a glob over paths split into segments, e.g. "/B2/*/B22".
+// a segment is matched like path.Match, and "**" matches any number of segments
+type pathPattern []string
+
+func compilePathPattern(pattern string) (pathPattern, error) {
+	if !strings.HasPrefix(pattern, string(separator)) {
+		return nil, fmt.Errorf("path pattern %q must start with %q", pattern, separator)
+	}
+
+	segs := splitPath(pattern)
+	for _, seg := range segs {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("path pattern %q: %w", pattern, err)
+		}
+	}
+	return segs, nil
+}
+
+// match reports whether the pattern matches segs or one of its ancestors.
+// if partial is true, it also reports whether it may match a descendant of segs
+func (p pathPattern) match(segs []string, partial bool) bool {
+	for len(p) > 0 {
+		if len(segs) == 0 {
+			if partial {
+				return true
+			}
+			for _, seg := range p {
+				if seg != recursiveToken {
+					return false
+				}
+			}
+			return true
+		}
+
+		if p[0] == recursiveToken {
+			for i := 0; i <= len(segs); i++ {
+				if p[1:].match(segs[i:], partial) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if ok, _ := path.Match(p[0], segs[0]); !ok {
+			return false
+		}
+		p, segs = p[1:], segs[1:]
+	}
+
+	return true
+}
+
+// capture reports whether the pattern matches the whole of segs, not only an ancestor,
+// and returns the segments matched by its wildcard segments in order.
+// "**" captures the segments it matches joined by the separator
+func (p pathPattern) capture(segs []string) ([]string, bool) {
+	return p.captureFrom(segs, nil)
+}
+
+func (p pathPattern) captureFrom(segs, captured []string) ([]string, bool) {
+	for len(p) > 0 {
+		if p[0] == recursiveToken {
+			for i := 0; i <= len(segs); i++ {
+				// cap the slice so the branches do not share appended segments
+				c := append(captured[:len(captured):len(captured)], strings.Join(segs[:i], string(separator)))
+				if c, ok := p[1:].captureFrom(segs[i:], c); ok {
+					return c, true
+				}
+			}
+			return nil, false
+		}
+
+		if len(segs) == 0 {
+			return nil, false
+		}
+		if ok, _ := path.Match(p[0], segs[0]); !ok {
+			return nil, false
+		}
+		if hasPatternSyntax(p[0]) {
+			captured = append(captured, segs[0])
+		}
+		p, segs = p[1:], segs[1:]
+	}
+
+	if len(segs) > 0 {
+		return nil, false
+	}
+	return captured, true
+}
+
+// wildcards returns the number of segments which are not literal
+func (p pathPattern) wildcards() int {
+	n := 0
+	for _, seg := range p {
+		if hasPatternSyntax(seg) {
+			n++
+		}
+	}
+	return n
+}
+
+// hasPatternSyntax reports whether s holds path.Match syntax, a path or segment without it
+// only matches itself
+func hasPatternSyntax(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+// pathFilter decides which paths become columns, an excluded path drops its
+// whole subtree, and with include patterns only the matched subtrees are kept
+type pathFilter struct {
+	include []pathPattern
+	exclude []pathPattern
+}
+
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	f := &pathFilter{}
+	for _, pattern := range include {
+		p, err := compilePathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, p)
+	}
+	for _, pattern := range exclude {
+		p, err := compilePathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, p)
+	}
+
+	return f, nil
+}
+
+// visit reports whether the subtree at path may hold a kept column
+func (f *pathFilter) visit(path string) bool {
+	return f.check(path, true)
+}
+
+// keep reports whether the column path is kept
+func (f *pathFilter) keep(path string) bool {
+	return f.check(path, false)
+}
+
+func (f *pathFilter) check(path string, partial bool) bool {
+	segs := splitPath(path)
+	for _, p := range f.exclude {
+		if p.match(segs, false) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.match(segs, partial) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits "/a/0/b" into "a", "0", "b"
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, string(separator))
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, string(separator))
+}
@@ -0,0 +1,140 @@
+package struct2csv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		visit   bool
+		keep    bool
+	}{
+		{name: "no include", exclude: []string{"/x"}, path: "/B1/0", visit: true, keep: true},
+		{name: "exact", include: []string{"/B2/*/B22"}, path: "/B2/0/B22", visit: true, keep: true},
+		{name: "ancestor of include", include: []string{"/B2/*/B22"}, path: "/B2/0", visit: true, keep: false},
+		{name: "sibling of include", include: []string{"/B2/*/B22"}, path: "/B2/0/B21", visit: false, keep: false},
+		{name: "descendant of include", include: []string{"/B2"}, path: "/B2/0/B21", visit: true, keep: true},
+		{name: "recursive", include: []string{"/meta/**"}, path: "/meta/a/b", visit: true, keep: true},
+		{name: "recursive in the middle", include: []string{"/**/B31/*"}, path: "/B3/0/B31/1", visit: true, keep: true},
+		{name: "segment glob", include: []string{"/B?"}, path: "/B1/0", visit: true, keep: true},
+		{name: "excluded subtree", exclude: []string{"/meta/**"}, path: "/meta", visit: false, keep: false},
+		{name: "exclude wins", include: []string{"/B2"}, exclude: []string{"/B2/*/B22"}, path: "/B2/0/B22", visit: false, keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newPathFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("newPathFilter() error = %v", err)
+			}
+			if got := f.visit(tt.path); got != tt.visit {
+				t.Errorf("visit(%s) = %v, want %v", tt.path, got, tt.visit)
+			}
+			if got := f.keep(tt.path); got != tt.keep {
+				t.Errorf("keep(%s) = %v, want %v", tt.path, got, tt.keep)
+			}
+		})
+	}
+}
+
+func Test_pathPattern_capture(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    []string
+		ok      bool
+	}{
+		{pattern: "/B2/*/B22", path: "/B2/0/B22", want: []string{"0"}, ok: true},
+		{pattern: "/B2/*/B22", path: "/B2/0/B22/x", ok: false},
+		{pattern: "/B2/*/B22", path: "/B2/0", ok: false},
+		{pattern: "/B?/*", path: "/B3/1", want: []string{"B3", "1"}, ok: true},
+		{pattern: "/meta/**", path: "/meta/a/b", want: []string{"a/b"}, ok: true},
+		{pattern: "/meta/**", path: "/meta", want: []string{""}, ok: true},
+		{pattern: "/**/B31/*", path: "/B3/0/B31/1", want: []string{"B3/0", "1"}, ok: true},
+		{pattern: "/**/B31/*", path: "/B3/0/B32/1", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, err := compilePathPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePathPattern() error = %v", err)
+			}
+			got, ok := p.capture(splitPath(tt.path))
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("capture() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewPathFilter_Error(t *testing.T) {
+	for _, pattern := range []string{"B1", "/B[1"} {
+		if _, err := newPathFilter([]string{pattern}, nil); err == nil {
+			t.Errorf("newPathFilter(%q) error = nil, want error", pattern)
+		}
+	}
+}
+
+func TestStructConverter_ConvertFilter(t *testing.T) {
+	data := []testStruct{{
+		A1: 1,
+		A2: true,
+		B1: []int{2, 3},
+		B2: []struct {
+			B21 int
+			B22 string
+		}{{B21: 4, B22: "a"}},
+		B3: []*struct {
+			B31 []*int
+		}{{B31: []*int{newInt(5)}}},
+	}}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		paths []string
+	}{
+		{
+			name:  "include",
+			opts:  []Option{WithInclude("/A1", "/B2/*/B22")},
+			paths: []string{"/A1", "/B2/0/B22"},
+		},
+		{
+			name:  "exclude",
+			opts:  []Option{WithExclude("/B*", "/A2")},
+			paths: []string{"/A1"},
+		},
+		{
+			name:  "include and exclude",
+			opts:  []Option{WithInclude("/B3/**"), WithExclude("/**/0/B31")},
+			paths: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, err := NewStructConverter(NewHeaderOriginalStringConv(), tt.opts...)
+			if err != nil {
+				t.Fatalf("NewStructConverter() error = %v", err)
+			}
+			got, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			paths := got.GetUnEncodedSortHeader()
+			if len(paths) == 0 {
+				paths = nil
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Convert() got UnEncodedSortHeader = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package struct2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// DescriptorSet holds the files and dynamic types of a FileDescriptorSet,
+// e.g. a .desc file written by protoc --descriptor_set_out.
+// Types can be passed to WithAnyResolver to unpack Any payloads of the same schema
+type DescriptorSet struct {
+	Files *protoregistry.Files
+	Types *protoregistry.Types
+}
+
+// ReadDescriptorSet reads a binary FileDescriptorSet.
+// dependencies missing from the set, e.g. google/protobuf/*.proto when protoc
+// ran without --include_imports, are taken from protoregistry.GlobalFiles
+func ReadDescriptorSet(r io.Reader) (*DescriptorSet, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("ReadDescriptorSet: %w", err)
+	}
+	if err := addGlobalDependencies(set); err != nil {
+		return nil, fmt.Errorf("ReadDescriptorSet: %w", err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDescriptorSet: %w", err)
+	}
+
+	types := new(protoregistry.Types)
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		err = registerDynamicTypes(types, fd.Messages(), fd.Extensions())
+		return err == nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ReadDescriptorSet: %w", err)
+	}
+
+	return &DescriptorSet{Files: files, Types: types}, nil
+}
+
+// FindMessageType returns the dynamic type of the message with the full name,
+// e.g. "pkg.Event"
+func (d *DescriptorSet) FindMessageType(name string) (protoreflect.MessageType, error) {
+	return d.Types.FindMessageByName(protoreflect.FullName(name))
+}
+
+func addGlobalDependencies(set *descriptorpb.FileDescriptorSet) error {
+	known := make(map[string]bool, len(set.File))
+	for _, f := range set.File {
+		known[f.GetName()] = true
+	}
+
+	for i := 0; i < len(set.File); i++ { // set.File grows while it is walked
+		for _, dep := range set.File[i].GetDependency() {
+			if known[dep] {
+				continue
+			}
+
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(dep)
+			if err != nil {
+				return fmt.Errorf("dependency %s: %w", dep, err)
+			}
+			known[dep] = true
+			set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+		}
+	}
+
+	return nil
+}
+
+func registerDynamicTypes(types *protoregistry.Types, messages protoreflect.MessageDescriptors, extensions protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < extensions.Len(); i++ {
+		if err := types.RegisterExtension(dynamicpb.NewExtensionType(extensions.Get(i))); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		if err := types.RegisterMessage(dynamicpb.NewMessageType(md)); err != nil {
+			return err
+		}
+		if err := registerDynamicTypes(types, md.Messages(), md.Extensions()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProtoFormat is the encoding of a protobuf message stream
+type ProtoFormat int
+
+const (
+	ProtoDelimited ProtoFormat = iota // binary messages, each prefixed with its varint encoded size
+	ProtoJSON                         // protojson objects, concatenated, one per line or inside one array
+)
+
+// ProtoDecoder decodes a stream of protobuf messages of one type,
+// the decoded messages can be passed to StructConverter.Convert
+type ProtoDecoder struct {
+	r        *bufio.Reader
+	mt       protoreflect.MessageType
+	format   ProtoFormat
+	resolver *protoregistry.Types
+
+	offset int64 // bytes of the delimited stream read so far
+
+	jsonDec   *json.Decoder
+	jsonArray bool // the json stream is a single top level array
+}
+
+// maxDelimitedSize is the largest size prefix ProtoDecoder accepts, protobuf limits
+// a message to less than 2 GiB
+const maxDelimitedSize = 1<<31 - 1
+
+// NewProtoDecoder returns a decoder of messages of type mt read from r.
+// resolver resolves extensions and Any payloads, it can be nil
+func NewProtoDecoder(r io.Reader, mt protoreflect.MessageType, format ProtoFormat, resolver *protoregistry.Types) *ProtoDecoder {
+	return &ProtoDecoder{
+		r:        bufio.NewReader(r),
+		mt:       mt,
+		format:   format,
+		resolver: resolver,
+	}
+}
+
+// Decode returns the next message, or io.EOF at the end of the stream
+func (d *ProtoDecoder) Decode() (proto.Message, error) {
+	if d.format == ProtoJSON {
+		return d.decodeJSON()
+	}
+	return d.decodeDelimited()
+}
+
+// DecodeAll returns all remaining messages of the stream
+func (d *ProtoDecoder) DecodeAll() ([]proto.Message, error) {
+	var msgs []proto.Message
+	for {
+		msg, err := d.Decode()
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func (d *ProtoDecoder) decodeDelimited() (proto.Message, error) {
+	start := d.offset
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("ProtoDecoder: message at offset %d: read size: %w", start, err)
+	}
+	d.offset += int64(uvarintLen(size))
+	if size > maxDelimitedSize {
+		return nil, fmt.Errorf("ProtoDecoder: message at offset %d: size %d exceeds %d bytes", start, size, maxDelimitedSize)
+	}
+
+	// the buffer grows with the bytes actually read, a corrupt size prefix does not
+	// allocate more than the rest of the stream
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, d.r, int64(size))
+	d.offset += n
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("ProtoDecoder: message at offset %d: read %d of %d bytes: %w", start, n, size, err)
+	}
+
+	msg := d.mt.New().Interface()
+	opts := proto.UnmarshalOptions{}
+	if d.resolver != nil {
+		opts.Resolver = d.resolver
+	}
+	if err := opts.Unmarshal(buf.Bytes(), msg); err != nil {
+		return nil, fmt.Errorf("ProtoDecoder: message at offset %d: %w", start, err)
+	}
+	return msg, nil
+}
+
+// uvarintLen returns the number of bytes of the varint encoding of v
+func uvarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+func (d *ProtoDecoder) decodeJSON() (proto.Message, error) {
+	if d.jsonDec == nil {
+		d.jsonDec = json.NewDecoder(d.r)
+		if err := d.openJSONArray(); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.jsonArray && !d.jsonDec.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := d.jsonDec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("ProtoDecoder: %w", err)
+	}
+
+	msg := d.mt.New().Interface()
+	opts := protojson.UnmarshalOptions{}
+	if d.resolver != nil {
+		opts.Resolver = d.resolver
+	}
+	if err := opts.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("ProtoDecoder: %w", err)
+	}
+	return msg, nil
+}
+
+// openJSONArray consumes the '[' of a stream holding one top level array
+func (d *ProtoDecoder) openJSONArray() error {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("ProtoDecoder: %w", err)
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		if err := d.r.UnreadByte(); err != nil {
+			return err
+		}
+		if c == '[' {
+			if _, err := d.jsonDec.Token(); err != nil {
+				return fmt.Errorf("ProtoDecoder: %w", err)
+			}
+			d.jsonArray = true
+		}
+		return nil
+	}
+}
@@ -0,0 +1,132 @@
+package struct2csv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestProtoDecoder(t *testing.T) {
+	set, err := ReadDescriptorSet(bytes.NewReader(testDescriptorSet(t, testEventFile(t))))
+	if err != nil {
+		t.Fatalf("ReadDescriptorSet() error = %v", err)
+	}
+	mt, err := set.FindMessageType("struct2csv.test.Event")
+	if err != nil {
+		t.Fatalf("FindMessageType() error = %v", err)
+	}
+
+	var delimited []byte
+	for _, js := range []string{`{"id": "e1", "tags": ["a", "b"]}`, `{"id": "e2", "point": {"x": 1}}`} {
+		b, err := proto.Marshal(newTestEvent(t, mt.Descriptor().ParentFile(), js))
+		if err != nil {
+			t.Fatal(err)
+		}
+		delimited = protowire.AppendBytes(delimited, b)
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		format ProtoFormat
+	}{
+		{
+			name:   "delimited",
+			input:  delimited,
+			format: ProtoDelimited,
+		},
+		{
+			name:   "json lines",
+			input:  []byte("{\"id\": \"e1\", \"tags\": [\"a\", \"b\"]}\n{\"id\": \"e2\", \"point\": {\"x\": 1}}\n"),
+			format: ProtoJSON,
+		},
+		{
+			name:   "json array",
+			input:  []byte(` [{"id": "e1", "tags": ["a", "b"]}, {"id": "e2", "point": {"x": 1}}]`),
+			format: ProtoJSON,
+		},
+	}
+
+	want := []string{"/id", "/point/x", "/tags/0", "/tags/1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := NewProtoDecoder(bytes.NewReader(tt.input), mt, tt.format, set.Types).DecodeAll()
+			if err != nil {
+				t.Fatalf("DecodeAll() error = %v", err)
+			}
+			if len(msgs) != 2 {
+				t.Fatalf("DecodeAll() got %d messages, want 2", len(msgs))
+			}
+
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			got, err := conv.Convert(msgs)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if paths := got.GetUnEncodedSortHeader(); !reflect.DeepEqual(paths, want) {
+				t.Errorf("Convert() got UnEncodedSortHeader = %v, want %v", paths, want)
+			}
+		})
+	}
+}
+
+func TestProtoDecoder_Error(t *testing.T) {
+	set, _ := ReadDescriptorSet(bytes.NewReader(testDescriptorSet(t, testEventFile(t))))
+	mt, _ := set.FindMessageType("struct2csv.test.Event")
+
+	_, err := NewProtoDecoder(strings.NewReader(`{"unknown": 1}`), mt, ProtoJSON, nil).DecodeAll()
+	if err == nil {
+		t.Error("DecodeAll() error = nil, want unknown field error")
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{
+			name:  "huge size",
+			input: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			want:  "message at offset 0: size 18446744073709551615 exceeds",
+		},
+		{
+			name:  "size beyond the stream",
+			input: append(protowire.AppendVarint([]byte{0}, 1<<30), 1, 2),
+			want:  "message at offset 1: read 2 of 1073741824 bytes",
+		},
+		{
+			name:  "truncated size",
+			input: []byte{0x80},
+			want:  "message at offset 0: read size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProtoDecoder(bytes.NewReader(tt.input), mt, ProtoDelimited, nil).DecodeAll()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DecodeAll() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// testDescriptorSet marshals a FileDescriptorSet of file without its dependencies,
+// like protoc without --include_imports
+func testDescriptorSet(t testing.TB, file protoreflect.FileDescriptor) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(file)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
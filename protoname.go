@@ -0,0 +1,99 @@
+package struct2csv
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtoFieldNaming decides which name of a protobuf field is used as its path token
+type ProtoFieldNaming int
+
+const (
+	ProtoTextName    ProtoFieldNaming = iota // fd.TextName(), e.g. "created_at", the default
+	ProtoJSONName                            // fd.JSONName(), e.g. "createdAt"
+	ProtoFullName                            // fd.FullName(), e.g. "pkg.Event.created_at"
+	ProtoFieldNumber                         // fd.Number(), e.g. "2"
+)
+
+// protoFieldName returns the path token of fd, the names are cached
+// because every row of the same message type asks for the same fields
+func (s *StructConverter) protoFieldName(fd protoreflect.FieldDescriptor) string {
+	if name, ok := s.protoNames[fd]; ok {
+		return name
+	}
+
+	name, ok := protoFieldLabel(fd, s.opts.protoLabel)
+	if !ok {
+		switch s.opts.protoNaming {
+		case ProtoJSONName:
+			name = fd.JSONName()
+		case ProtoFullName:
+			name = string(fd.FullName())
+		case ProtoFieldNumber:
+			name = strconv.Itoa(int(fd.Number()))
+		default:
+			name = fd.TextName()
+		}
+	}
+
+	if s.protoNames == nil {
+		s.protoNames = make(map[protoreflect.FieldDescriptor]string)
+	}
+	s.protoNames[fd] = name
+	return name
+}
+
+// protoFieldLabel reads the string field option xt of fd
+func protoFieldLabel(fd protoreflect.FieldDescriptor, xt protoreflect.ExtensionType) (string, bool) {
+	if xt == nil {
+		return "", false
+	}
+
+	opts := fd.Options()
+	if opts == nil {
+		return "", false
+	}
+
+	if !proto.HasExtension(opts, xt) {
+		// descriptors built at runtime keep options they could not resolve as unknown fields,
+		// and those of a descriptor set extend their own copy of FieldOptions, so the
+		// option is read by its number
+		xd := xt.TypeDescriptor()
+		if xd.Kind() != protoreflect.StringKind || xd.IsList() {
+			return "", false
+		}
+		label := unknownString(opts.ProtoReflect().GetUnknown(), xd.Number())
+		return label, label != ""
+	}
+
+	label, ok := proto.GetExtension(opts, xt).(string)
+	return label, ok && label != ""
+}
+
+// unknownString returns the last string field num of the wire encoded fields b
+func unknownString(b []byte, num protoreflect.FieldNumber) string {
+	var s string
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return s
+		}
+		b = b[l:]
+		if n == num && typ == protowire.BytesType {
+			v, l := protowire.ConsumeString(b)
+			if l < 0 {
+				return s
+			}
+			s = v
+		}
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		if l < 0 {
+			return s
+		}
+		b = b[l:]
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package struct2csv
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// wildcardToken stands for every index of a repeated field or key of a map field in a schema path
+const wildcardToken = "*"
+
+// SchemaForMessage returns the leaf paths a message of type md can produce,
+// in field declaration order and named like Convert would name them.
+// repeated and map fields use "*" in place of the index or key, e.g. "/tags/*",
+// bytes fields are left out because they never become a column.
+// a recursive message type is expanded only once on each path
+func (s *StructConverter) SchemaForMessage(md protoreflect.MessageDescriptor) []string {
+	var paths []string
+	s.walkProtoSchema(md, NewPathBuilder(s.opts.strBuilderCap), true, map[protoreflect.FullName]bool{}, func(path string) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// walkProtoSchema calls visit with the path of every leaf field of md below prefix,
+// repeated and map fields are skipped unless wildcards is true
+func (s *StructConverter) walkProtoSchema(md protoreflect.MessageDescriptor, prefix PathBuilder, wildcards bool,
+	visiting map[protoreflect.FullName]bool, visit func(path string)) {
+	if visiting[md.FullName()] {
+		return
+	}
+	visiting[md.FullName()] = true
+	defer delete(visiting, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if (fd.IsList() || fd.IsMap()) && !wildcards {
+			continue
+		}
+
+		pointer := prefix.Clone(s.opts.strBuilderCap)
+		pointer.AppendString(s.protoFieldName(fd))
+		if fd.IsList() || fd.IsMap() {
+			pointer.AppendString(wildcardToken)
+		}
+		if !s.visit(pointer) {
+			continue
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			s.walkProtoSchema(fd.Message(), pointer, wildcards, visiting, visit)
+		case protoreflect.BytesKind:
+		default:
+			visit(pointer.String())
+		}
+	}
+}
+
+// flattenProtoUnpopulated emits the columns of a field msg.Range did not visit.
+// a field with presence stays empty to tell unset apart from zero, a message field
+// only registers the columns of its own fields, any other field gets its default value
+func (s *StructConverter) flattenProtoUnpopulated(out *KeyValue, msg protoreflect.Message, fd protoreflect.FieldDescriptor, key PathBuilder) error {
+	switch {
+	case fd.IsList() || fd.IsMap():
+		// no index or key to name a column after
+		return nil
+	case fd.Message() != nil:
+		s.walkProtoSchema(fd.Message(), key, false, map[protoreflect.FullName]bool{}, func(path string) {
+			s.register(path)
+		})
+		return nil
+	case fd.HasPresence():
+		if fd.Kind() != protoreflect.BytesKind {
+			s.register(key.String())
+		}
+		return nil
+	default:
+		return s.flattenProto(out, fd, msg.Get(fd), key)
+	}
+}
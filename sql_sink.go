@@ -0,0 +1,336 @@
+package struct2csv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithSQLTxSize sets the rows SQLSink inserts per transaction, the default is 10000
+func WithSQLTxSize(rows int) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.txSize = rows
+	}
+}
+
+// WithSQLAddColumns sets whether SQLSink adds the columns of new paths to an existing table
+// with ALTER TABLE ADD COLUMN, the default is true. without it new paths are an error
+func WithSQLAddColumns(p bool) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.addColumns = p
+	}
+}
+
+// SQLSink inserts KVs into a table of a database/sql database, creating the table
+// from the columns of the first KVs unless WithSQLCreateTable(false) is given.
+// the table is looked up by its unqualified name in the catalog of the dialect, e.g.
+// sqlite_master, and the columns of an existing table are only checked by name
+type SQLSink struct {
+	db      *sql.DB
+	opts    *sqlOptions
+	columns map[string]bool // lower case column names of the table, nil until the table is checked
+}
+
+// NewSQLSink returns new SQLSink, it takes the options of SQLWriter
+func NewSQLSink(db *sql.DB, options ...SQLOption) *SQLSink {
+	opts := &sqlOptions{
+		table:       "data",
+		batchSize:   100,
+		createTable: true,
+		txSize:      10000,
+		addColumns:  true,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &SQLSink{
+		db:   db,
+		opts: opts,
+	}
+}
+
+// Load inserts the rows of results, the values are consumed like by CSVWriter.WriteCSV.
+// rows of transactions committed before an error stay in the table
+func (s *SQLSink) Load(ctx context.Context, results *KVs) error {
+	if s.opts.batchSize <= 0 || s.opts.txSize <= 0 {
+		return fmt.Errorf("Load: batch size %d and transaction size %d", s.opts.batchSize, s.opts.txSize)
+	}
+	columns, err := sqlColumns(results.Columns(), s.opts.headerConv)
+	if err != nil {
+		return fmt.Errorf("Load: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	if err := s.prepareTable(ctx, columns); err != nil {
+		return fmt.Errorf("Load: %w", err)
+	}
+
+	l := &sqlLoad{sink: s, columns: columns}
+	err = results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		return l.add(ctx, values)
+	})
+	if err == nil {
+		err = l.commit(ctx)
+	}
+	if err != nil {
+		if l.tx != nil {
+			l.tx.Rollback()
+		}
+		return fmt.Errorf("Load: %w", err)
+	}
+	return nil
+}
+
+// prepareTable creates the table, or adds the columns it misses
+func (s *SQLSink) prepareTable(ctx context.Context, columns []sqlColumn) error {
+	d := s.opts.dialect
+	if s.columns == nil {
+		exists, err := s.tableExists(ctx)
+		if err != nil {
+			return err
+		}
+		switch {
+		case exists:
+			if s.columns, err = s.tableColumns(ctx); err != nil {
+				return err
+			}
+		case s.opts.createTable:
+			if _, err := s.db.ExecContext(ctx, d.createTable(s.opts.table, columns)); err != nil {
+				return err
+			}
+			s.columns = make(map[string]bool, len(columns))
+			for _, c := range columns {
+				s.columns[strings.ToLower(c.name)] = true
+			}
+			return nil
+		default:
+			return fmt.Errorf("table %s does not exist", s.opts.table)
+		}
+	}
+
+	for _, c := range columns {
+		if s.columns[strings.ToLower(c.name)] {
+			continue
+		}
+		if !s.opts.addColumns {
+			return fmt.Errorf("table %s has no column %s for %s", s.opts.table, c.name, c.path)
+		}
+		stmt := "ALTER TABLE " + d.quoteIdent(s.opts.table) + " ADD COLUMN " + d.quoteIdent(c.name) + " " + d.columnType(c.valueType)
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+		s.columns[strings.ToLower(c.name)] = true
+	}
+	return nil
+}
+
+// tableExists looks the table up in the catalog, so that a failing query, e.g. for a lost
+// connection or a missing permission, is not taken for a missing table
+func (s *SQLSink) tableExists(ctx context.Context) (bool, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.opts.dialect.tableExistsQuery(), s.opts.table).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// tableColumns returns the columns of the table
+func (s *SQLSink) tableColumns(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT * FROM "+s.opts.dialect.quoteIdent(s.opts.table)+" WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[strings.ToLower(name)] = true
+	}
+	return columns, rows.Close()
+}
+
+// tableExistsQuery returns the query counting the tables and views named by its parameter
+// which an unqualified name refers to
+func (d SQLDialect) tableExistsQuery() string {
+	switch d {
+	case MySQL:
+		return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	case SQLite:
+		return "SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name = ? COLLATE NOCASE"
+	default:
+		return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = ANY (current_schemas(false)) AND table_name = $1"
+	}
+}
+
+// maxParams returns the most placeholders one statement of d may hold
+func (d SQLDialect) maxParams() int {
+	if d == SQLite {
+		return 32766
+	}
+	return 65535
+}
+
+// insertStatement returns the INSERT of rows rows with placeholders
+func (d SQLDialect) insertStatement(table string, columns []sqlColumn, rows int) string {
+	var b strings.Builder
+	b.WriteString(d.insertInto(table, columns))
+	b.WriteString(" VALUES ")
+	n := 0
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := range columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			n++
+			if d == PostgreSQL {
+				b.WriteByte('$')
+				b.WriteString(strconv.Itoa(n))
+			} else {
+				b.WriteByte('?')
+			}
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// arg converts v for a column holding values of t to a value every database/sql driver takes:
+// int64, float64, bool, string or time.Time
+func (d SQLDialect) arg(t ValueType, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch t {
+	case ValueTypeInt, ValueTypeUint, ValueTypeFloat, ValueTypeNumber:
+		if n, ok := v.(json.Number); ok {
+			return n.String()
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u := rv.Uint(); u > math.MaxInt64 {
+				return strconv.FormatUint(u, 10)
+			}
+			return int64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return rv.Float()
+		}
+	case ValueTypeBool:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case ValueTypeTime:
+		if tm, ok := v.(time.Time); ok {
+			return tm
+		}
+	}
+	return toString(v)
+}
+
+// sqlLoad is the state of one SQLSink.Load
+type sqlLoad struct {
+	sink    *SQLSink
+	columns []sqlColumn
+	tx      *sql.Tx
+	txRows  int
+	args    []interface{} // values of the rows of the pending batch
+	stmt    *sql.Stmt     // INSERT of a full batch, prepared on the first full batch of a transaction
+}
+
+func (l *sqlLoad) batchRows() int {
+	rows := l.sink.opts.batchSize
+	if max := l.sink.opts.dialect.maxParams() / len(l.columns); rows > max {
+		rows = max
+	}
+	return rows
+}
+
+func (l *sqlLoad) add(ctx context.Context, values []interface{}) error {
+	d := l.sink.opts.dialect
+	for i, v := range values {
+		l.args = append(l.args, d.arg(l.columns[i].valueType, v))
+	}
+	if len(l.args) < l.batchRows()*len(l.columns) {
+		return nil
+	}
+	if err := l.flush(ctx); err != nil {
+		return err
+	}
+	if l.txRows >= l.sink.opts.txSize {
+		return l.commit(ctx)
+	}
+	return nil
+}
+
+// flush inserts the pending batch
+func (l *sqlLoad) flush(ctx context.Context) error {
+	rows := len(l.args) / len(l.columns)
+	if rows == 0 {
+		return nil
+	}
+	if l.tx == nil {
+		tx, err := l.sink.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		l.tx = tx
+	}
+
+	d := l.sink.opts.dialect
+	var err error
+	if rows == l.batchRows() {
+		if l.stmt == nil {
+			l.stmt, err = l.tx.PrepareContext(ctx, d.insertStatement(l.sink.opts.table, l.columns, rows))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = l.stmt.ExecContext(ctx, l.args...)
+	} else {
+		_, err = l.tx.ExecContext(ctx, d.insertStatement(l.sink.opts.table, l.columns, rows), l.args...)
+	}
+	if err != nil {
+		return err
+	}
+
+	l.txRows += rows
+	l.args = l.args[:0]
+	return nil
+}
+
+// commit inserts the pending batch and commits the transaction
+func (l *sqlLoad) commit(ctx context.Context) error {
+	if err := l.flush(ctx); err != nil {
+		return err
+	}
+	if l.tx == nil {
+		return nil
+	}
+	if l.stmt != nil {
+		l.stmt.Close()
+		l.stmt = nil
+	}
+	err := l.tx.Commit()
+	l.tx = nil
+	l.txRows = 0
+	return err
+}
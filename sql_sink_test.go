@@ -0,0 +1,246 @@
+package struct2csv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the catalog lookups of SQLSink, sqlitetest runs the SQLite one against the engine
+const (
+	pgTableExists     = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = ANY (current_schemas(false)) AND table_name = $1`
+	sqliteTableExists = `SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name = ? COLLATE NOCASE`
+)
+
+func TestSQLSink_Load(t *testing.T) {
+	type row struct {
+		ID   int64
+		Name string
+	}
+	rows := []row{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+
+	tests := []struct {
+		name      string
+		tables    map[string][]string
+		failQuery string
+		opts      []SQLOption
+		want      []string
+		wantErr   bool
+		wantArgs  [][]interface{}
+	}{
+		{
+			name: "create",
+			opts: []SQLOption{WithSQLBatchSize(2)},
+			want: []string{
+				pgTableExists,
+				"CREATE TABLE \"data\" (\n  \"/ID\" BIGINT,\n  \"/Name\" TEXT\n)",
+				"BEGIN",
+				`PREPARE INSERT INTO "data" ("/ID", "/Name") VALUES ($1, $2), ($3, $4)`,
+				`INSERT INTO "data" ("/ID", "/Name") VALUES ($1, $2)`,
+				"COMMIT",
+			},
+			wantArgs: [][]interface{}{{int64(1), "a", int64(2), "b"}, {int64(3), "c"}},
+		},
+		{
+			name:   "add column",
+			tables: map[string][]string{"events": {"/id"}},
+			opts:   []SQLOption{WithSQLDialect(SQLite), WithSQLTable("events"), WithSQLTxSize(1), WithSQLBatchSize(1)},
+			want: []string{
+				sqliteTableExists,
+				`SELECT * FROM "events" WHERE 1 = 0`,
+				`ALTER TABLE "events" ADD COLUMN "/Name" TEXT`,
+				"BEGIN",
+				`PREPARE INSERT INTO "events" ("/ID", "/Name") VALUES (?, ?)`,
+				"COMMIT",
+				"BEGIN",
+				`PREPARE INSERT INTO "events" ("/ID", "/Name") VALUES (?, ?)`,
+				"COMMIT",
+				"BEGIN",
+				`PREPARE INSERT INTO "events" ("/ID", "/Name") VALUES (?, ?)`,
+				"COMMIT",
+			},
+			wantArgs: [][]interface{}{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}},
+		},
+		{
+			name:    "missing column",
+			tables:  map[string][]string{"data": {"/ID"}},
+			opts:    []SQLOption{WithSQLAddColumns(false)},
+			want:    []string{pgTableExists, `SELECT * FROM "data" WHERE 1 = 0`},
+			wantErr: true,
+		},
+		{
+			name:    "missing table",
+			opts:    []SQLOption{WithSQLCreateTable(false)},
+			want:    []string{pgTableExists},
+			wantErr: true,
+		},
+		{
+			name:      "failing lookup",
+			failQuery: "SELECT COUNT(*)",
+			want:      []string{pgTableExists},
+			wantErr:   true,
+		},
+		{
+			name:      "failing probe of an existing table",
+			tables:    map[string][]string{"data": {"/ID", "/Name"}},
+			failQuery: "SELECT * FROM",
+			want:      []string{pgTableExists, `SELECT * FROM "data" WHERE 1 = 0`},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), WithResultCap(len(rows)))
+			kvs, err := conv.Convert(rows)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			fake := &fakeSQLConnector{tables: tt.tables, failQuery: tt.failQuery}
+			db := sql.OpenDB(fake)
+			defer db.Close()
+
+			err = NewSQLSink(db, tt.opts...).Load(context.Background(), kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fake.log, tt.want) {
+				t.Errorf("Load() statements = %q, want %q", fake.log, tt.want)
+			}
+			if !reflect.DeepEqual(fake.args, tt.wantArgs) {
+				t.Errorf("Load() args = %v, want %v", fake.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSQLSink_LoadRollback(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, _ := conv.Convert([]struct{ ID int64 }{{ID: 1}, {ID: 2}})
+
+	fake := &fakeSQLConnector{failExec: "INSERT"}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	if err := NewSQLSink(db).Load(context.Background(), kvs); err == nil {
+		t.Fatalf("Load() error = nil, want insert error")
+	}
+	if last := fake.log[len(fake.log)-1]; last != "ROLLBACK" {
+		t.Errorf("Load() last statement = %q, want ROLLBACK", last)
+	}
+}
+
+// fakeSQLConnector is a database/sql driver recording the statements it gets.
+// "SELECT COUNT(*)" counts the tables named by its argument, and "SELECT * FROM"
+// answers with the columns of tables, or fails for other tables
+type fakeSQLConnector struct {
+	tables    map[string][]string
+	failExec  string // statements starting with it fail
+	failQuery string // queries starting with it fail
+	log       []string
+	args      [][]interface{}
+}
+
+func (c *fakeSQLConnector) Connect(context.Context) (driver.Conn, error) { return &fakeSQLConn{c}, nil }
+func (c *fakeSQLConnector) Driver() driver.Driver                        { return nil }
+
+type fakeSQLConn struct{ c *fakeSQLConnector }
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	c.c.log = append(c.c.log, "PREPARE "+query)
+	return &fakeSQLStmt{c: c.c, query: query}, nil
+}
+func (c *fakeSQLConn) Close() error { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.c.log = append(c.c.log, "BEGIN")
+	return c, nil
+}
+func (c *fakeSQLConn) Commit() error {
+	c.c.log = append(c.c.log, "COMMIT")
+	return nil
+}
+func (c *fakeSQLConn) Rollback() error {
+	c.c.log = append(c.c.log, "ROLLBACK")
+	return nil
+}
+
+// ExecContext runs statements which are not prepared explicitly
+func (c *fakeSQLConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.c.log = append(c.c.log, query)
+	return (&fakeSQLStmt{c: c.c, query: query}).exec(args)
+}
+
+func (c *fakeSQLConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.c.log = append(c.c.log, query)
+	if c.c.failQuery != "" && strings.HasPrefix(query, c.c.failQuery) {
+		return nil, errors.New("query failed")
+	}
+	if strings.HasPrefix(query, "SELECT COUNT(*)") {
+		_, ok := c.c.tables[args[0].Value.(string)]
+		n := int64(0)
+		if ok {
+			n = 1
+		}
+		return &fakeSQLRows{columns: []string{"count"}, values: [][]driver.Value{{n}}}, nil
+	}
+	for table, columns := range c.c.tables {
+		if strings.HasPrefix(query, `SELECT * FROM "`+table+`"`) {
+			return &fakeSQLRows{columns: columns}, nil
+		}
+	}
+	return nil, errors.New("no such table")
+}
+
+type fakeSQLStmt struct {
+	c     *fakeSQLConnector
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	named := make([]driver.NamedValue, 0, len(args))
+	for i, v := range args {
+		named = append(named, driver.NamedValue{Ordinal: i + 1, Value: v})
+	}
+	return s.exec(named)
+}
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeSQLStmt) exec(args []driver.NamedValue) (driver.Result, error) {
+	if s.c.failExec != "" && strings.HasPrefix(s.query, s.c.failExec) {
+		return nil, errors.New("exec failed")
+	}
+	if len(args) > 0 {
+		values := make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			values = append(values, arg.Value)
+		}
+		s.c.args = append(s.c.args, values)
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+type fakeSQLRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
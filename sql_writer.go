@@ -0,0 +1,409 @@
+package struct2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect is the SQL flavour of the statements SQLWriter writes
+type SQLDialect int
+
+const (
+	PostgreSQL SQLDialect = iota
+	MySQL                 // assumes the default sql_mode, in which backslash escapes strings
+	SQLite
+)
+
+type sqlOptions struct {
+	dialect     SQLDialect
+	table       string
+	headerConv  HeaderConverter // converts paths to column names, nil uses the encoded header
+	batchSize   int             // rows per INSERT statement
+	createTable bool            // write CREATE TABLE ahead of the rows, or SQLSink creates a missing table
+	txSize      int             // rows per transaction of SQLSink
+	addColumns  bool            // SQLSink adds missing columns to an existing table
+}
+
+type SQLOption func(opts *sqlOptions)
+
+// WithSQLDialect sets the dialect, the default is PostgreSQL
+func WithSQLDialect(d SQLDialect) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.dialect = d
+	}
+}
+
+// WithSQLTable sets the table name, the default is "data". the name is quoted as one identifier
+func WithSQLTable(name string) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.table = name
+	}
+}
+
+// WithSQLHeaderConverter names the columns by converting their paths with conv,
+// e.g. NewHeaderCaseConv(SnakeCase, CollisionSuffix), instead of using the encoded header
+func WithSQLHeaderConverter(conv HeaderConverter) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.headerConv = conv
+	}
+}
+
+// WithSQLBatchSize sets the rows per INSERT statement, the default is 100
+func WithSQLBatchSize(rows int) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.batchSize = rows
+	}
+}
+
+// WithSQLCreateTable sets whether a CREATE TABLE statement is written ahead of the rows,
+// or SQLSink creates a missing table, the default is true
+func WithSQLCreateTable(p bool) SQLOption {
+	return func(opts *sqlOptions) {
+		opts.createTable = p
+	}
+}
+
+// SQLWriter writes a CREATE TABLE statement with column types inferred from the values,
+// followed by multi-row INSERT statements
+type SQLWriter struct {
+	w    *bufio.Writer
+	opts *sqlOptions
+	buf  []byte
+}
+
+// NewSQLWriter returns new SQLWriter
+func NewSQLWriter(w io.Writer, options ...SQLOption) *SQLWriter {
+	opts := &sqlOptions{
+		table:       "data",
+		batchSize:   100,
+		createTable: true,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &SQLWriter{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+		buf:  make([]byte, 0, 4096),
+	}
+}
+
+// WriteSQL writes the statements of results, the values are consumed like by CSVWriter.WriteCSV.
+// results without columns are an error if the table is created, a table needs a column,
+// and write nothing with WithSQLCreateTable(false)
+func (w *SQLWriter) WriteSQL(results *KVs) error {
+	if w.opts.batchSize <= 0 {
+		return fmt.Errorf("WriteSQL: batch size %d", w.opts.batchSize)
+	}
+	columns, err := sqlColumns(results.Columns(), w.opts.headerConv)
+	if err != nil {
+		return fmt.Errorf("WriteSQL: %w", err)
+	}
+	if len(columns) == 0 {
+		if w.opts.createTable {
+			return fmt.Errorf("WriteSQL: no columns to create table %s with", w.opts.table)
+		}
+		return nil
+	}
+
+	d := w.opts.dialect
+	if w.opts.createTable {
+		w.w.WriteString(d.createTable(w.opts.table, columns))
+		w.w.WriteString(";\n")
+	}
+
+	insert := d.insertInto(w.opts.table, columns) + " VALUES\n"
+	n := 0
+	err = results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		w.buf = w.buf[:0]
+		if n == 0 {
+			w.buf = append(w.buf, insert...)
+		} else {
+			w.buf = append(w.buf, ",\n"...)
+		}
+		w.buf = append(w.buf, '(')
+		for i, v := range values {
+			if i > 0 {
+				w.buf = append(w.buf, ", "...)
+			}
+			w.buf = d.appendLiteral(w.buf, columns[i].valueType, v)
+		}
+		w.buf = append(w.buf, ')')
+
+		if n++; n == w.opts.batchSize {
+			w.buf = append(w.buf, ";\n"...)
+			n = 0
+		}
+		_, err := w.w.Write(w.buf)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("WriteSQL: %w", err)
+	}
+	if n > 0 {
+		w.w.WriteString(";\n")
+	}
+
+	if err := w.w.Flush(); err != nil {
+		return fmt.Errorf("WriteSQL: %w", err)
+	}
+	return nil
+}
+
+type sqlColumn struct {
+	name      string
+	path      string
+	valueType ValueType
+}
+
+// sqlColumns names the columns with conv, or by their encoded header if conv is nil
+func sqlColumns(columns []ColumnInfo, conv HeaderConverter) ([]sqlColumn, error) {
+	out := make([]sqlColumn, 0, len(columns))
+	seen := make(map[string]string, len(columns))
+	for _, c := range columns {
+		name := c.Code
+		if conv != nil {
+			name = conv.ConvertHeader(c.Path).String()
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty column name for %s", c.Path)
+		}
+		// MySQL and SQLite compare column names case insensitive
+		if other, ok := seen[strings.ToLower(name)]; ok {
+			return nil, fmt.Errorf("paths %s and %s both give column %s", other, c.Path, name)
+		}
+		seen[strings.ToLower(name)] = c.Path
+		out = append(out, sqlColumn{name: name, path: c.Path, valueType: c.Type})
+	}
+	if e, ok := conv.(interface{ Err() error }); ok && e.Err() != nil {
+		return nil, e.Err()
+	}
+	return out, nil
+}
+
+func (d SQLDialect) String() string {
+	switch d {
+	case PostgreSQL:
+		return "postgresql"
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	default:
+		return "SQLDialect(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// ParseSQLDialect returns the dialect of name, e.g. "postgresql" or "postgres"
+func ParseSQLDialect(name string) (SQLDialect, error) {
+	switch strings.ToLower(name) {
+	case "postgresql", "postgres", "pg":
+		return PostgreSQL, nil
+	case "mysql", "mariadb":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	default:
+		return 0, errors.New("unknown SQL dialect " + strconv.Quote(name))
+	}
+}
+
+func (d SQLDialect) quoteIdent(name string) string {
+	if d == MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// columnType returns the type of a column holding values of t
+func (d SQLDialect) columnType(t ValueType) string {
+	switch d {
+	case MySQL:
+		switch t {
+		case ValueTypeInt:
+			return "BIGINT"
+		case ValueTypeUint:
+			return "BIGINT UNSIGNED"
+		case ValueTypeFloat:
+			return "DOUBLE"
+		case ValueTypeNumber:
+			return "DECIMAL(65,30)"
+		case ValueTypeBool:
+			return "BOOLEAN"
+		case ValueTypeTime:
+			return "DATETIME(6)"
+		default:
+			return "LONGTEXT"
+		}
+	case SQLite:
+		switch t {
+		case ValueTypeInt, ValueTypeUint, ValueTypeBool:
+			return "INTEGER"
+		case ValueTypeFloat:
+			return "REAL"
+		case ValueTypeNumber:
+			return "NUMERIC"
+		default:
+			return "TEXT"
+		}
+	default:
+		switch t {
+		case ValueTypeInt:
+			return "BIGINT"
+		case ValueTypeUint:
+			return "NUMERIC(20)"
+		case ValueTypeFloat:
+			return "DOUBLE PRECISION"
+		case ValueTypeNumber:
+			return "NUMERIC"
+		case ValueTypeBool:
+			return "BOOLEAN"
+		case ValueTypeTime:
+			return "TIMESTAMPTZ"
+		default:
+			return "TEXT"
+		}
+	}
+}
+
+func (d SQLDialect) createTable(table string, columns []sqlColumn) string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE ")
+	b.WriteString(d.quoteIdent(table))
+	b.WriteString(" (\n")
+	for i, c := range columns {
+		b.WriteString("  ")
+		b.WriteString(d.quoteIdent(c.name))
+		b.WriteByte(' ')
+		b.WriteString(d.columnType(c.valueType))
+		if i < len(columns)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func (d SQLDialect) insertInto(table string, columns []sqlColumn) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(d.quoteIdent(table))
+	b.WriteString(" (")
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.quoteIdent(c.name))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// appendLiteral appends v as literal of a column holding values of t, nil as NULL
+func (d SQLDialect) appendLiteral(b []byte, t ValueType, v interface{}) []byte {
+	if v == nil {
+		return append(b, "NULL"...)
+	}
+
+	switch v := d.value(t, v).(type) {
+	case nil:
+		return append(b, "NULL"...)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case float64:
+		return strconv.AppendFloat(b, v, 'g', -1, 64)
+	case json.Number:
+		return append(b, v...)
+	case bool:
+		if d == SQLite {
+			if v {
+				return append(b, '1')
+			}
+			return append(b, '0')
+		}
+		return strconv.AppendBool(b, v)
+	default:
+		return d.appendString(b, v.(string))
+	}
+}
+
+// value converts v for a column holding values of t: int64, uint64, float64, json.Number,
+// bool or string. floats PostgreSQL can not hold as number are strings, other dialects get nil,
+// and a json.Number which is not a JSON number is a string
+func (d SQLDialect) value(t ValueType, v interface{}) interface{} {
+	switch t {
+	case ValueTypeInt, ValueTypeUint, ValueTypeFloat, ValueTypeNumber:
+		if n, ok := v.(json.Number); ok {
+			if !isJSONNumber(string(n)) {
+				return string(n) // not a number, a quoted string can not break out of the literal
+			}
+			return n
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if (math.IsNaN(f) || math.IsInf(f, 0)) && d != PostgreSQL {
+				return nil
+			}
+			switch {
+			case math.IsNaN(f):
+				return "NaN"
+			case math.IsInf(f, 1):
+				return "Infinity"
+			case math.IsInf(f, -1):
+				return "-Infinity"
+			}
+			return f
+		}
+	case ValueTypeBool:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case ValueTypeTime:
+		if tm, ok := v.(time.Time); ok {
+			if d == MySQL {
+				return tm.UTC().Format("2006-01-02 15:04:05.999999")
+			}
+			return tm.Format(time.RFC3339Nano)
+		}
+	}
+	return toString(v)
+}
+
+// appendString appends s as quoted string literal. PostgreSQL and SQLite can not hold NUL
+// in text, it is dropped
+func (d SQLDialect) appendString(b []byte, s string) []byte {
+	b = append(b, '\'')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			b = append(b, '\'', '\'')
+		case d == MySQL && c == '\\':
+			b = append(b, '\\', '\\')
+		case c == 0 && d == MySQL:
+			b = append(b, '\\', '0')
+		case c == 0:
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '\'')
+}
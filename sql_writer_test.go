@@ -0,0 +1,186 @@
+package struct2csv
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLWriter_WriteSQL(t *testing.T) {
+	type row struct {
+		UserID int64
+		Name   string
+		Active bool
+		Score  float64
+		At     time.Time
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := []row{
+		{UserID: 1, Name: "O'Neil \\ x", Active: true, Score: 1.5, At: at},
+		{UserID: 2, Name: "b"},
+		{UserID: 3},
+	}
+
+	tests := []struct {
+		name string
+		opts []SQLOption
+		want string
+	}{
+		{
+			name: "postgresql",
+			opts: []SQLOption{WithSQLBatchSize(2), WithSQLHeaderConverter(NewHeaderCaseConv(SnakeCase, CollisionError))},
+			want: `CREATE TABLE "data" (
+  "active" BOOLEAN,
+  "at" TIMESTAMPTZ,
+  "name" TEXT,
+  "score" DOUBLE PRECISION,
+  "user_id" BIGINT
+);
+INSERT INTO "data" ("active", "at", "name", "score", "user_id") VALUES
+(true, '2024-01-02T03:04:05Z', 'O''Neil \ x', 1.5, 1),
+(NULL, NULL, 'b', NULL, 2);
+INSERT INTO "data" ("active", "at", "name", "score", "user_id") VALUES
+(NULL, NULL, NULL, NULL, 3);
+`,
+		},
+		{
+			name: "mysql",
+			opts: []SQLOption{WithSQLDialect(MySQL), WithSQLTable("users")},
+			want: "CREATE TABLE `users` (\n" +
+				"  `/Active` BOOLEAN,\n" +
+				"  `/At` DATETIME(6),\n" +
+				"  `/Name` LONGTEXT,\n" +
+				"  `/Score` DOUBLE,\n" +
+				"  `/UserID` BIGINT\n" +
+				");\n" +
+				"INSERT INTO `users` (`/Active`, `/At`, `/Name`, `/Score`, `/UserID`) VALUES\n" +
+				"(true, '2024-01-02 03:04:05', 'O''Neil \\\\ x', 1.5, 1),\n" +
+				"(NULL, NULL, 'b', NULL, 2),\n" +
+				"(NULL, NULL, NULL, NULL, 3);\n",
+		},
+		{
+			name: "sqlite without create",
+			opts: []SQLOption{WithSQLDialect(SQLite), WithSQLCreateTable(false), WithSQLBatchSize(3)},
+			want: `INSERT INTO "data" ("/Active", "/At", "/Name", "/Score", "/UserID") VALUES
+(1, '2024-01-02T03:04:05Z', 'O''Neil \ x', 1.5, 1),
+(NULL, NULL, 'b', NULL, 2),
+(NULL, NULL, NULL, NULL, 3);
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv(), WithResultCap(len(data)))
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewSQLWriter(&b, tt.opts...).WriteSQL(kvs); err != nil {
+				t.Fatalf("WriteSQL() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteSQL() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLWriter_WriteSQLCollision(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, _ := conv.Convert([]map[string]interface{}{{"a_b": 1, "aB": 2}})
+
+	err := NewSQLWriter(&strings.Builder{}, WithSQLHeaderConverter(NewHeaderCaseConv(SnakeCase, CollisionError))).WriteSQL(kvs)
+	if err == nil {
+		t.Errorf("WriteSQL() error = nil, want collision")
+	}
+}
+
+func TestSQLWriter_WriteSQLNoColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []SQLOption
+		wantErr bool
+	}{
+		{name: "create table", wantErr: true},
+		{name: "inserts only", opts: []SQLOption{WithSQLCreateTable(false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, _ := conv.Convert([]struct{ A int }{{}})
+
+			var b strings.Builder
+			err := NewSQLWriter(&b, tt.opts...).WriteSQL(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteSQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if b.Len() != 0 {
+				t.Errorf("WriteSQL() = %q, want nothing", b.String())
+			}
+		})
+	}
+}
+
+func TestSQLWriter_WriteSQLIntAndUint(t *testing.T) {
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert([]map[string]interface{}{{"n": int64(-1)}, {"n": uint64(math.MaxUint64)}})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewSQLWriter(&b).WriteSQL(kvs); err != nil {
+		t.Fatalf("WriteSQL() error = %v", err)
+	}
+	want := "CREATE TABLE \"data\" (\n  \"/n\" NUMERIC\n);\n" +
+		"INSERT INTO \"data\" (\"/n\") VALUES\n(-1),\n(18446744073709551615);\n"
+	if b.String() != want {
+		t.Errorf("WriteSQL() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestSQLDialect_appendLiteral(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   SQLDialect
+		valueType ValueType
+		v         interface{}
+		want      string
+	}{
+		{name: "number in text column", dialect: PostgreSQL, valueType: ValueTypeString, v: int64(1), want: "'1'"},
+		{name: "int in float column", dialect: PostgreSQL, valueType: ValueTypeFloat, v: int64(2), want: "2"},
+		{name: "uint", dialect: MySQL, valueType: ValueTypeUint, v: uint64(math.MaxUint64), want: "18446744073709551615"},
+		{name: "postgresql NaN", dialect: PostgreSQL, valueType: ValueTypeFloat, v: math.NaN(), want: "'NaN'"},
+		{name: "postgresql -Inf", dialect: PostgreSQL, valueType: ValueTypeFloat, v: math.Inf(-1), want: "'-Infinity'"},
+		{name: "mysql Inf", dialect: MySQL, valueType: ValueTypeFloat, v: math.Inf(1), want: "NULL"},
+		{name: "mysql NUL", dialect: MySQL, valueType: ValueTypeString, v: "a\x00b", want: `'a\0b'`},
+		{name: "sqlite NUL", dialect: SQLite, valueType: ValueTypeString, v: "a\x00b", want: "'ab'"},
+		{name: "sqlite false", dialect: SQLite, valueType: ValueTypeBool, v: false, want: "0"},
+		{name: "json number", dialect: MySQL, valueType: ValueTypeNumber, v: json.Number("-1.5e3"), want: "-1.5e3"},
+		{name: "json number injection", dialect: MySQL, valueType: ValueTypeNumber, v: json.Number("1); DROP TABLE data; --"), want: "'1); DROP TABLE data; --'"},
+		{name: "json number quote", dialect: PostgreSQL, valueType: ValueTypeNumber, v: json.Number("1' OR '1'='1"), want: "'1'' OR ''1''=''1'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.dialect.appendLiteral(nil, tt.valueType, tt.v)); got != tt.want {
+				t.Errorf("appendLiteral() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSQLDialect(t *testing.T) {
+	for _, d := range []SQLDialect{PostgreSQL, MySQL, SQLite} {
+		if got, err := ParseSQLDialect(d.String()); err != nil || got != d {
+			t.Errorf("ParseSQLDialect(%q) = %v, %v, want %v", d.String(), got, err, d)
+		}
+	}
+	if _, err := ParseSQLDialect("oracle"); err == nil {
+		t.Errorf("ParseSQLDialect(oracle) error = nil")
+	}
+}
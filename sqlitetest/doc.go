@@ -0,0 +1,3 @@
+// Package sqlitetest runs SQLSink and SQLWriter against a real SQLite engine. it is a module
+// of its own so that the SQLite driver does not become a dependency of struct2csv
+package sqlitetest
@@ -0,0 +1,146 @@
+package sqlitetest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"struct2csv"
+)
+
+type event struct {
+	ID   int64
+	Name string
+}
+
+type scoredEvent struct {
+	ID    int64
+	Name  string
+	Score float64
+	Ok    bool
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func convert(t *testing.T, data interface{}) *struct2csv.KVs {
+	t.Helper()
+	conv, _ := struct2csv.NewStructConverter(struct2csv.NewHeaderCaseConv(struct2csv.SnakeCase, struct2csv.CollisionError))
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	return kvs
+}
+
+func queryRows(t *testing.T, db *sql.DB, query string) [][]interface{} {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	defer rows.Close()
+
+	columns, _ := rows.Columns()
+	var got [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestSQLSink_Load(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	opts := []struct2csv.SQLOption{
+		struct2csv.WithSQLDialect(struct2csv.SQLite),
+		struct2csv.WithSQLTable("events"),
+		struct2csv.WithSQLHeaderConverter(struct2csv.NewHeaderCaseConv(struct2csv.SnakeCase, struct2csv.CollisionError)),
+		struct2csv.WithSQLBatchSize(2),
+		struct2csv.WithSQLTxSize(3),
+	}
+
+	// creates the table
+	first := convert(t, []event{{ID: 1, Name: "a"}, {ID: 2, Name: "it's"}, {ID: 3, Name: "c"}})
+	if err := struct2csv.NewSQLSink(db, opts...).Load(ctx, first); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// a new sink finds the table in sqlite_master and adds the new columns
+	second := convert(t, []scoredEvent{{ID: 4, Name: "d", Score: 0.5, Ok: true}})
+	if err := struct2csv.NewSQLSink(db, opts...).Load(ctx, second); err != nil {
+		t.Fatalf("Load() of new columns error = %v", err)
+	}
+
+	got := queryRows(t, db, `SELECT "id", "name", "score", "ok" FROM "events" ORDER BY "id"`)
+	want := [][]interface{}{
+		{int64(1), "a", nil, nil},
+		{int64(2), "it's", nil, nil},
+		{int64(3), "c", nil, nil},
+		{int64(4), "d", 0.5, int64(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestSQLSink_LoadErrors(t *testing.T) {
+	ctx := context.Background()
+	kvs := convert(t, []event{{ID: 1, Name: "a"}})
+
+	db := openDB(t)
+	err := struct2csv.NewSQLSink(db, struct2csv.WithSQLDialect(struct2csv.SQLite), struct2csv.WithSQLCreateTable(false)).Load(ctx, kvs)
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Load() of a missing table error = %v, want does not exist", err)
+	}
+
+	// a failing lookup is returned as it is, not taken for a missing table to create
+	db.Close()
+	kvs = convert(t, []event{{ID: 1, Name: "a"}})
+	err = struct2csv.NewSQLSink(db, struct2csv.WithSQLDialect(struct2csv.SQLite)).Load(ctx, kvs)
+	if err == nil || strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Load() on a closed database error = %v, want the lookup error", err)
+	}
+}
+
+func TestSQLWriter_WriteSQL(t *testing.T) {
+	db := openDB(t)
+	kvs := convert(t, []scoredEvent{{ID: 1, Name: "a'b", Score: 1.5, Ok: true}, {ID: 2}})
+
+	var b strings.Builder
+	if err := struct2csv.NewSQLWriter(&b, struct2csv.WithSQLDialect(struct2csv.SQLite), struct2csv.WithSQLTable("events")).WriteSQL(kvs); err != nil {
+		t.Fatalf("WriteSQL() error = %v", err)
+	}
+	if _, err := db.Exec(b.String()); err != nil {
+		t.Fatalf("Exec() of\n%s\nerror = %v", b.String(), err)
+	}
+
+	got := queryRows(t, db, `SELECT "id", "name", "score", "ok" FROM "events" ORDER BY "id"`)
+	want := [][]interface{}{{int64(1), "a'b", 1.5, int64(1)}, {int64(2), nil, nil, nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
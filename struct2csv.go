@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 // HeaderConverter will convert the path string of the original struct field
@@ -19,6 +21,8 @@ type HeaderConverter interface {
 // compile time checks
 var _ HeaderConverter = (*HeaderAutoIncrementConv)(nil)
 var _ HeaderConverter = (*HeaderOriginalStringConv)(nil)
+var _ HeaderConverter = (*HeaderDictionaryConv)(nil)
+var _ HeaderConverter = (*HeaderCaseConv)(nil)
 
 type Option func(opts *Options)
 
@@ -42,6 +46,21 @@ type Options struct {
 	isObjArray    bool // if u know the input data is must the map|struct of slice, set it true
 	strBuilderCap int  // pre-allocated for strings.Builder Cap size, call the Grow function
 	rowSize       int  // pre-allocated for KeyValue map size
+
+	anyResolver   protoregistry.MessageTypeResolver // unpack google.protobuf.Any payloads, nil keeps them packed
+	anyTypeColumn bool                              // add a "<path>/$type" column for every unpacked Any
+
+	protoNaming ProtoFieldNaming           // which name of a protobuf field becomes its path token
+	protoLabel  protoreflect.ExtensionType // string field option overriding protoNaming, nil means unused
+
+	emitUnpopulated bool // emit columns for protobuf fields that are not populated
+	typeColumn      bool // add "<path>/$type" discriminator columns for oneofs and interface fields
+	insertionOrder  bool // order the headers by first appearance instead of sorting them
+	orderSet        bool // WithInsertionOrder was given, ConvertJSON keeps insertionOrder as is
+	objArraySet     bool // WithIsObjArray was given, ConvertJSON follows isObjArray
+
+	include []string // path patterns of the kept subtrees, empty keeps all
+	exclude []string // path patterns of the dropped subtrees
 }
 
 func WithResultCap(p int) Option {
@@ -50,9 +69,13 @@ func WithResultCap(p int) Option {
 	}
 }
 
+// WithIsObjArray sets whether every element of the top level slice is a row, the default.
+// false makes it a row per element only if the elements are maps, structs or pointers,
+// and a single row otherwise. ConvertJSON detects it the same way unless WithIsObjArray(true) is given
 func WithIsObjArray(p bool) Option {
 	return func(opts *Options) {
 		opts.isObjArray = p
+		opts.objArraySet = true
 	}
 }
 
@@ -68,6 +91,97 @@ func WithRowSize(p int) Option {
 	}
 }
 
+// WithAnyResolver sets the resolver used to unpack google.protobuf.Any fields,
+// e.g. protoregistry.GlobalTypes or a *protoregistry.Types.
+// the payload is flattened as its concrete message under the Any field's path.
+// an Any whose type can not be found is flattened as the Any message itself, which
+// gives a type_url column, its value is dropped like every bytes field
+func WithAnyResolver(r protoregistry.MessageTypeResolver) Option {
+	return func(opts *Options) {
+		opts.anyResolver = r
+	}
+}
+
+// WithAnyTypeColumn adds a "<path>/$type" column holding the full name of the
+// unpacked Any payload, the column name of WithTypeColumn.
+// it only takes effect together with WithAnyResolver
+func WithAnyTypeColumn(p bool) Option {
+	return func(opts *Options) {
+		opts.anyTypeColumn = p
+	}
+}
+
+// WithProtoFieldNaming sets which name of a protobuf field is used in the path,
+// the default is ProtoTextName
+func WithProtoFieldNaming(p ProtoFieldNaming) Option {
+	return func(opts *Options) {
+		opts.protoNaming = p
+	}
+}
+
+// WithProtoFieldLabel sets a custom string field option, e.g.
+//
+//	extend google.protobuf.FieldOptions { string column = 50000; }
+//
+// fields carrying it use the option value as path token instead of their name
+func WithProtoFieldLabel(xt protoreflect.ExtensionType) Option {
+	return func(opts *Options) {
+		opts.protoLabel = xt
+	}
+}
+
+// WithEmitUnpopulated mirrors protojson's EmitUnpopulated, unpopulated protobuf
+// fields still produce their columns so every row of a message type has the same width.
+// fields without presence are filled with their default value, fields with presence
+// (proto3 optional, oneof members and messages) are left empty so unset differs from zero.
+// repeated and map fields have no columns when they are empty
+func WithEmitUnpopulated(p bool) Option {
+	return func(opts *Options) {
+		opts.emitUnpopulated = p
+	}
+}
+
+// WithTypeColumn adds a "<path>/$type" discriminator column to polymorphic values:
+// for a protobuf oneof it sits under the oneof name and holds the name of the chosen case,
+// for a struct field of a non-empty interface type it holds the Go type name of the value,
+// e.g. "main.Circle", or the message full name if the value is a protobuf message.
+// the elements of a slice, e.g. the rows of a []proto.Message, get none
+func WithTypeColumn(p bool) Option {
+	return func(opts *Options) {
+		opts.typeColumn = p
+	}
+}
+
+// WithInsertionOrder orders the headers by the first appearance of each path
+// instead of sorting them. ConvertJSON and ConvertNDJSON use insertion order
+// unless WithInsertionOrder(false) is given.
+// the order of Go map keys is random
+func WithInsertionOrder(p bool) Option {
+	return func(opts *Options) {
+		opts.insertionOrder = p
+		opts.orderSet = true
+	}
+}
+
+// WithInclude keeps only the columns below paths matching one of the patterns.
+// a pattern is a path whose segments are matched like path.Match, "*" matches one
+// segment and "**" any number of them, e.g. "/B2/*/B22" or "/meta/**".
+// subtrees that can not match are never walked
+func WithInclude(patterns ...string) Option {
+	return func(opts *Options) {
+		opts.include = append(opts.include, patterns...)
+	}
+}
+
+// WithExclude drops the columns below paths matching one of the patterns,
+// see WithInclude for the syntax. excluded subtrees are never walked,
+// exclusion wins over inclusion
+func WithExclude(patterns ...string) Option {
+	return func(opts *Options) {
+		opts.exclude = append(opts.exclude, patterns...)
+	}
+}
+
 func defaultOpts() *Options {
 	return &Options{
 		resultCap:     50,
@@ -81,6 +195,8 @@ type StructConverter struct {
 	kvs        *KVs
 	opts       *Options
 	headerConv HeaderConverter
+	protoNames map[protoreflect.FieldDescriptor]string // cache of protoFieldName
+	filter     *pathFilter                             // nil if every path is kept
 }
 
 // NewStructConverter a converter can convert struct to csv kv
@@ -93,13 +209,65 @@ func NewStructConverter(headerConv HeaderConverter, opts ...Option) (*StructConv
 		opts:       loadOptions(opts...),
 		headerConv: headerConv,
 	}
+
+	filter, err := newPathFilter(sc.opts.include, sc.opts.exclude)
+	if err != nil {
+		return nil, err
+	}
+	sc.filter = filter
 	sc.kvs = NewKVs(sc.opts.resultCap, sc.opts.rowSize)
+	sc.kvs.insertionOrder = sc.opts.insertionOrder
 
 	return sc, nil
 }
 
 // Convert converts Struct to CSV key value
 func (s *StructConverter) Convert(data interface{}) (*KVs, error) {
+	kvs, err := s.convert(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.headerConvErr(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// describeOptions returns the options which change the content of the result, for a bundle manifest
+func (s *StructConverter) describeOptions() map[string]interface{} {
+	opts := map[string]interface{}{
+		"header_converter": fmt.Sprintf("%T", s.headerConv),
+		"proto_naming":     int(s.opts.protoNaming),
+	}
+	if len(s.opts.include) > 0 {
+		opts["include"] = s.opts.include
+	}
+	if len(s.opts.exclude) > 0 {
+		opts["exclude"] = s.opts.exclude
+	}
+	for name, on := range map[string]bool{
+		"any_resolver":     s.opts.anyResolver != nil,
+		"any_type_column":  s.opts.anyTypeColumn,
+		"emit_unpopulated": s.opts.emitUnpopulated,
+		"type_column":      s.opts.typeColumn,
+		"insertion_order":  s.kvs.insertionOrder,
+	} {
+		if on {
+			opts[name] = true
+		}
+	}
+	return opts
+}
+
+// headerConvErr returns the error of a HeaderConverter that can fail, e.g. HeaderCaseConv
+func (s *StructConverter) headerConvErr() error {
+	if conv, ok := s.headerConv.(interface{ Err() error }); ok {
+		return conv.Err()
+	}
+	return nil
+}
+
+func (s *StructConverter) convert(data interface{}) (*KVs, error) {
 	v := valueOf(data)
 	sliceIterator := func() error {
 		for i := 0; i < v.Len(); i++ {
@@ -221,6 +389,8 @@ func toString(obj interface{}) string {
 		return strconv.FormatFloat(v, 'f', 10, 64)
 	case string:
 		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
 	default:
 		return fmt.Sprintf("%v", obj)
 	}
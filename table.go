@@ -0,0 +1,147 @@
+package struct2csv
+
+import (
+	"strconv"
+)
+
+const ellipsis = "…"
+
+type tableOptions struct {
+	maxWidth       int  // cells wider than maxWidth are cut, 0 keeps them whole
+	originalHeader bool // label the columns by path instead of encoded header
+	groupHeader    bool // one header row per path level, parents spanning their children
+}
+
+// TableOption configures the writers rendering KVs as a table for people to read
+type TableOption func(opts *tableOptions)
+
+// WithTableMaxWidth cuts cells wider than n columns of a terminal, ending them with "…".
+// an East Asian wide character takes two columns. 0 keeps them whole
+func WithTableMaxWidth(n int) TableOption {
+	return func(opts *tableOptions) {
+		opts.maxWidth = n
+	}
+}
+
+// WithTableOriginalHeader labels the columns by path, e.g. "/B2/0/B21", instead of the encoded header
+func WithTableOriginalHeader(p bool) TableOption {
+	return func(opts *tableOptions) {
+		opts.originalHeader = p
+	}
+}
+
+// WithTableGroupHeader writes one header row per path level, e.g. "B2" spanning the
+// columns "/B2/0/B21" and "/B2/0/B22" above "0" and then "B21" and "B22".
+// the labels are path segments, so the encoded header is not used
+func WithTableGroupHeader(p bool) TableOption {
+	return func(opts *tableOptions) {
+		opts.groupHeader = p
+	}
+}
+
+func newTableOptions(options []TableOption) *tableOptions {
+	opts := &tableOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// label returns the single row header of column
+func (o *tableOptions) label(column ColumnInfo) string {
+	if o.originalHeader {
+		return column.Path
+	}
+	return column.Code
+}
+
+// truncate cuts s to maxWidth columns of displayWidth
+func (o *tableOptions) truncate(s string) string {
+	if o.maxWidth <= 0 || displayWidth(s) <= o.maxWidth {
+		return s
+	}
+
+	n := 0
+	for i, r := range s {
+		n += runeWidth(r)
+		if n > o.maxWidth-1 { // leave a column for the ellipsis
+			return s[:i] + ellipsis
+		}
+	}
+	return s
+}
+
+// displayString formats v for people, floats without trailing zeros. nil is empty
+func displayString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return toString(v)
+	}
+}
+
+// headerCell is one cell of a multi-level header
+type headerCell struct {
+	label   string
+	column  int // the first column the cell spans
+	colspan int
+	rowspan int
+}
+
+// headerLevels returns the rows of a header with one row per path level. a segment
+// shared by neighbouring columns is one cell spanning them, the last segment of a
+// path shorter than the deepest spans the rows below it
+func headerLevels(paths []string) [][]headerCell {
+	segs := make([][]string, len(paths))
+	depth := 1
+	for i, path := range paths {
+		segs[i] = splitPath(path)
+		if len(segs[i]) == 0 {
+			segs[i] = []string{""}
+		}
+		if len(segs[i]) > depth {
+			depth = len(segs[i])
+		}
+	}
+
+	levels := make([][]headerCell, depth)
+	for l := 0; l < depth; l++ {
+		for i, s := range segs {
+			if l >= len(s) {
+				continue
+			}
+			leaf := l == len(s)-1
+			if !leaf {
+				if cells := levels[l]; len(cells) > 0 {
+					last := &cells[len(cells)-1]
+					prev := segs[last.column]
+					if last.rowspan == 1 && last.column+last.colspan == i && len(prev) > l+1 && samePrefix(prev, s, l+1) {
+						last.colspan++
+						continue
+					}
+				}
+			}
+
+			rowspan := 1
+			if leaf {
+				rowspan = depth - l
+			}
+			levels[l] = append(levels[l], headerCell{label: s[l], column: i, colspan: 1, rowspan: rowspan})
+		}
+	}
+	return levels
+}
+
+func samePrefix(a, b []string, n int) bool {
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package struct2csv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_headerLevels(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  [][]headerCell
+	}{
+		{
+			name:  "flat",
+			paths: []string{"/A", "/B"},
+			want:  [][]headerCell{{{"A", 0, 1, 1}, {"B", 1, 1, 1}}},
+		},
+		{
+			name:  "nested",
+			paths: []string{"/A", "/B2/0/B21", "/B2/0/B22", "/B2/1/B21", "/C/D"},
+			want: [][]headerCell{
+				{{"A", 0, 1, 3}, {"B2", 1, 3, 1}, {"C", 4, 1, 1}},
+				{{"0", 1, 2, 1}, {"1", 3, 1, 1}, {"D", 4, 1, 2}},
+				{{"B21", 1, 1, 1}, {"B22", 2, 1, 1}, {"B21", 3, 1, 1}},
+			},
+		},
+		{
+			name:  "parent and child columns",
+			paths: []string{"/A", "/A/B", "/A/C"},
+			want: [][]headerCell{
+				{{"A", 0, 1, 2}, {"A", 1, 2, 1}},
+				{{"B", 1, 1, 1}, {"C", 2, 1, 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headerLevels(tt.paths); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("headerLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tableOptions_truncate(t *testing.T) {
+	tests := []struct {
+		maxWidth int
+		s        string
+		want     string
+	}{
+		{0, "abcdef", "abcdef"},
+		{6, "abcdef", "abcdef"},
+		{4, "abcdef", "abc…"},
+		{3, "日本語です", "日…"},
+		{5, "日本語です", "日本…"},
+		{4, "日本", "日本"},
+		{2, "日x", "…"},
+		{1, "ab", "…"},
+	}
+	for _, tt := range tests {
+		o := newTableOptions([]TableOption{WithTableMaxWidth(tt.maxWidth)})
+		if got := o.truncate(tt.s); got != tt.want {
+			t.Errorf("truncate(%q) with %d = %q, want %q", tt.s, tt.maxWidth, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package struct2csv
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// escapeControl keeps a value on one line of the terminal and its escape sequences from
+// reaching it: line breaks and tabs become \n, \r and \t, the other control characters of
+// unicode.IsControl, e.g. ESC, DEL or the C1 CSI, become \x1b, \x7f and \u009b
+func escapeControl(s string) string {
+	return escapeControls(s, false)
+}
+
+// escapeRecord is escapeControl for records read back by programs, it escapes the
+// backslash too so that an escaped tab can be told from a backslash followed by t
+func escapeRecord(s string) string {
+	return escapeControls(s, true)
+}
+
+func escapeControls(s string, backslash bool) string {
+	clean := true
+	for _, r := range s {
+		if unicode.IsControl(r) || backslash && r == '\\' {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\\' && backslash:
+			b.WriteString(`\\`)
+		case r < 0x80 && unicode.IsControl(r):
+			b.WriteString(`\x`)
+			b.WriteString(hex2(int(r)))
+		case unicode.IsControl(r):
+			b.WriteString(`\u00`)
+			b.WriteString(hex2(int(r)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// hex2 formats a byte as two lower case hex digits
+func hex2(c int) string {
+	const digits = "0123456789abcdef"
+	return string([]byte{digits[c>>4], digits[c&0xf]})
+}
+
+// TextTableWriter writes KVs for a terminal, as an aligned table like psql or as
+// one block of path and value per record like psql \x. the rows are held in
+// memory to align them, it is meant for a few records while debugging.
+// WithTableGroupHeader does not apply
+type TextTableWriter struct {
+	w    *bufio.Writer
+	opts *tableOptions
+}
+
+// NewTextTableWriter returns new TextTableWriter
+func NewTextTableWriter(w io.Writer, options ...TableOption) *TextTableWriter {
+	return &TextTableWriter{
+		w:    bufio.NewWriter(w),
+		opts: newTableOptions(options),
+	}
+}
+
+// WriteTable writes the rows of results under a header, numeric columns are right aligned.
+// the values are consumed like by CSVWriter.WriteCSV
+func (w *TextTableWriter) WriteTable(results *KVs) error {
+	columns := results.Columns()
+	if len(columns) == 0 {
+		return nil
+	}
+
+	header := make([]string, 0, len(columns))
+	widths := make([]int, 0, len(columns))
+	for _, c := range columns {
+		label := w.cell(w.opts.label(c))
+		header = append(header, label)
+		widths = append(widths, displayWidth(label))
+	}
+	rows, err := w.collect(results, widths)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for i, label := range header {
+		w.appendField(&b, i, label, widths[i], false)
+	}
+	w.endLine(&b)
+	for i, width := range widths {
+		if i > 0 {
+			b.WriteByte('+')
+		}
+		b.WriteString(strings.Repeat("-", width+2))
+	}
+	w.endLine(&b)
+	for _, row := range rows {
+		for i, v := range row {
+			w.appendField(&b, i, v, widths[i], isNumericType(columns[i].Type))
+		}
+		w.endLine(&b)
+	}
+
+	return w.w.Flush()
+}
+
+// WriteVertical writes one block per record listing the path and value of every column
+// holding a value, e.g.
+//
+//	-[ RECORD 1 ]------
+//	/B1         | 1
+//	/B2/0/B21   | a
+//
+// the values are consumed like by CSVWriter.WriteCSV
+func (w *TextTableWriter) WriteVertical(results *KVs) error {
+	columns := results.Columns()
+	labels := make([]string, 0, len(columns))
+	labelWidth := 0
+	for _, c := range columns {
+		label := w.cell(c.Path)
+		labels = append(labels, label)
+		if n := displayWidth(label); n > labelWidth {
+			labelWidth = n
+		}
+	}
+
+	record := 0
+	var b strings.Builder
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		record++
+		b.Reset()
+		valueWidth := 0
+		cells := make([]string, len(values))
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			cells[i] = w.cell(displayString(v))
+			if n := displayWidth(cells[i]); n > valueWidth {
+				valueWidth = n
+			}
+		}
+
+		title := "-[ RECORD " + strconv.Itoa(record) + " ]"
+		b.WriteString(title)
+		if n := labelWidth + 3 + valueWidth - len(title); n > 0 {
+			b.WriteString(strings.Repeat("-", n))
+		}
+		b.WriteByte('\n')
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			b.WriteString(labels[i])
+			b.WriteString(strings.Repeat(" ", labelWidth-displayWidth(labels[i])))
+			b.WriteString(" | ")
+			b.WriteString(cells[i])
+			b.WriteByte('\n')
+		}
+		_, err := w.w.WriteString(b.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
+
+// collect returns the cells of every row and widens widths to fit them
+func (w *TextTableWriter) collect(results *KVs, widths []int) ([][]string, error) {
+	var rows [][]string
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = w.cell(displayString(v))
+			if n := displayWidth(row[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+func (w *TextTableWriter) cell(s string) string {
+	return w.opts.truncate(escapeControl(s))
+}
+
+func (w *TextTableWriter) appendField(b *strings.Builder, i int, s string, width int, right bool) {
+	if i > 0 {
+		b.WriteString("|")
+	}
+	pad := strings.Repeat(" ", width-displayWidth(s))
+	b.WriteByte(' ')
+	if right {
+		b.WriteString(pad)
+		b.WriteString(s)
+	} else {
+		b.WriteString(s)
+		b.WriteString(pad)
+	}
+	b.WriteByte(' ')
+}
+
+// endLine ends the line without the trailing spaces of the last field
+func (w *TextTableWriter) endLine(b *strings.Builder) {
+	line := strings.TrimRight(b.String(), " ")
+	w.w.WriteString(line)
+	w.w.WriteByte('\n')
+	b.Reset()
+}
+
+// displayWidth returns the terminal columns s takes: East Asian wide characters take two,
+// combining marks none
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth returns the columns r takes, 0 for combining marks
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200b':
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+func isWide(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f || // Hangul Jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f || // CJK ... Yi
+		r >= 0xac00 && r <= 0xd7a3 || // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff || // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f || // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60 || // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6 ||
+		r >= 0x1f300 && r <= 0x1f64f || // pictographs and emoticons
+		r >= 0x1f900 && r <= 0x1f9ff ||
+		r >= 0x20000 && r <= 0x3fffd)
+}
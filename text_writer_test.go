@@ -0,0 +1,127 @@
+package struct2csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextTableWriter_WriteTable(t *testing.T) {
+	type row struct {
+		Name  string
+		Count int
+	}
+	data := []row{{Name: "日本", Count: 1}, {Name: "tab\there", Count: 120}, {Name: "x"}}
+
+	tests := []struct {
+		name string
+		opts []TableOption
+		want string
+	}{
+		{
+			name: "aligned",
+			want: " /Count | /Name\n" +
+				"--------+-----------\n" +
+				"      1 | 日本\n" +
+				"    120 | tab\\there\n" +
+				"        | x\n",
+		},
+		{
+			name: "truncated",
+			opts: []TableOption{WithTableMaxWidth(4)},
+			want: " /Co… | /Na…\n" +
+				"------+------\n" +
+				"    1 | 日本\n" +
+				"  120 | tab…\n" +
+				"      | x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, err := conv.Convert(data)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			var b strings.Builder
+			if err := NewTextTableWriter(&b, tt.opts...).WriteTable(kvs); err != nil {
+				t.Fatalf("WriteTable() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteTable() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTextTableWriter_WriteVertical(t *testing.T) {
+	type inner struct {
+		B21 string
+		B22 float64
+	}
+	type row struct {
+		B1 int
+		B2 []inner
+	}
+	data := []row{{B1: 1, B2: []inner{{B21: "a", B22: 0.25}}}, {B2: []inner{{B21: "line\nbreak"}}}}
+
+	conv, _ := NewStructConverter(NewHeaderAutoIncrementConv())
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := NewTextTableWriter(&b).WriteVertical(kvs); err != nil {
+		t.Fatalf("WriteVertical() error = %v", err)
+	}
+	want := "-[ RECORD 1 ]---\n" +
+		"/B1       | 1\n" +
+		"/B2/0/B21 | a\n" +
+		"/B2/0/B22 | 0.25\n" +
+		"-[ RECORD 2 ]----------\n" +
+		"/B2/0/B21 | line\\nbreak\n"
+	if b.String() != want {
+		t.Errorf("WriteVertical() = \n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func Test_displayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"日本語", 6},
+		{"한국", 4},
+		{"é", 1},
+		{"ｱ", 1},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func Test_escapeControls(t *testing.T) {
+	tests := []struct {
+		s         string
+		backslash bool
+		want      string
+	}{
+		{s: "plain 日本", want: "plain 日本"},
+		{s: "a\r\nb\tc", want: `a\r\nb\tc`},
+		{s: "\x1b[31mred\x1b[0m", want: `\x1b[31mred\x1b[0m`},
+		{s: "nul\x00del\x7f", want: `nul\x00del\x7f`},
+		{s: "csi\u009b2J", want: `csi\u009b2J`},
+		{s: `C:\t`, want: `C:\t`},
+		{s: "C:\\t\t", backslash: true, want: `C:\\t\t`},
+	}
+	for _, tt := range tests {
+		if got := escapeControls(tt.s, tt.backslash); got != tt.want {
+			t.Errorf("escapeControls(%q, %v) = %q, want %q", tt.s, tt.backslash, got, tt.want)
+		}
+	}
+}
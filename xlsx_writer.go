@@ -0,0 +1,440 @@
+package struct2csv
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	xlsxMaxSheetName = 31
+	xlsxMaxRows      = 1048576
+	xlsxMaxColumns   = 16384
+	xlsxMaxExact     = 1 << 53 // larger integers lose digits as Excel numbers and are written as text
+)
+
+// cell styles, the indexes of cellXfs in xlsxStyles
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleTime
+)
+
+var (
+	xlsxEpoch     = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC) // serial 0 for dates from 1900-03-01
+	xlsxMarch1900 = time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)   // serials from here on count the 1900-02-29 Excel believes in
+)
+
+type xlsxOptions struct {
+	sheetName    string   // name of the data sheet
+	mappingSheet string   // name of the mapping sheet, empty leaves it out
+	timeLayouts  []string // string values parsing with one of them become date cells
+}
+
+type XLSXOption func(opts *xlsxOptions)
+
+// WithXLSXSheetName sets the name of the data sheet, the default is "data"
+func WithXLSXSheetName(name string) XLSXOption {
+	return func(opts *xlsxOptions) {
+		opts.sheetName = name
+	}
+}
+
+// WithXLSXMappingSheet adds a second sheet with the path, code and type of every column,
+// so encoded headers can be read back. an empty name leaves it out, which is the default
+func WithXLSXMappingSheet(name string) XLSXOption {
+	return func(opts *xlsxOptions) {
+		opts.mappingSheet = name
+	}
+}
+
+// WithXLSXTimeLayouts writes string values parsing with one of the layouts as date cells,
+// e.g. time.RFC3339 for timestamps of JSON input. time.Time values always are date cells
+func WithXLSXTimeLayouts(layouts ...string) XLSXOption {
+	return func(opts *xlsxOptions) {
+		opts.timeLayouts = append(opts.timeLayouts, layouts...)
+	}
+}
+
+// XLSXWriter writes an Excel workbook with one worksheet of the rows.
+// numbers, bools and times become typed cells and strings stay text, so leading
+// zeros survive. the header row is frozen
+type XLSXWriter struct {
+	w    io.Writer
+	opts *xlsxOptions
+	buf  []byte
+}
+
+// NewXLSXWriter returns new XLSXWriter
+func NewXLSXWriter(w io.Writer, options ...XLSXOption) *XLSXWriter {
+	opts := &xlsxOptions{
+		sheetName: "data",
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return &XLSXWriter{
+		w:    w,
+		opts: opts,
+		buf:  make([]byte, 0, 4096),
+	}
+}
+
+// WriteXLSX writes the workbook of results, the values are consumed like by CSVWriter.WriteCSV
+func (w *XLSXWriter) WriteXLSX(results *KVs) error {
+	sheets := []string{w.opts.sheetName}
+	if w.opts.mappingSheet != "" {
+		sheets = append(sheets, w.opts.mappingSheet)
+	}
+	if err := checkSheetNames(sheets); err != nil {
+		return fmt.Errorf("WriteXLSX: %w", err)
+	}
+
+	columns := results.Columns()
+	if len(columns) > xlsxMaxColumns {
+		return fmt.Errorf("WriteXLSX: %d columns, a sheet holds at most %d", len(columns), xlsxMaxColumns)
+	}
+	if results.Rows()+1 > xlsxMaxRows {
+		return fmt.Errorf("WriteXLSX: %d rows, a sheet holds at most %d", results.Rows()+1, xlsxMaxRows)
+	}
+
+	zw := zip.NewWriter(w.w)
+	files := []struct {
+		name  string
+		write func(w io.Writer) error
+	}{
+		{"[Content_Types].xml", func(w io.Writer) error { return writeXLSXContentTypes(w, len(sheets)) }},
+		{"_rels/.rels", func(w io.Writer) error { return writeXLSXString(w, xlsxRootRels) }},
+		{"xl/workbook.xml", func(w io.Writer) error { return writeXLSXWorkbook(w, sheets) }},
+		{"xl/_rels/workbook.xml.rels", func(w io.Writer) error { return writeXLSXWorkbookRels(w, len(sheets)) }},
+		{"xl/styles.xml", func(w io.Writer) error { return writeXLSXString(w, xlsxStyles) }},
+		{"xl/worksheets/sheet1.xml", func(bw io.Writer) error { return w.writeDataSheet(bw, results, columns) }},
+	}
+	if w.opts.mappingSheet != "" {
+		files = append(files, struct {
+			name  string
+			write func(w io.Writer) error
+		}{"xl/worksheets/sheet2.xml", func(bw io.Writer) error { return w.writeMappingSheet(bw, columns) }})
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("WriteXLSX: %w", err)
+		}
+		if err := file.write(fw); err != nil {
+			return fmt.Errorf("WriteXLSX: %s: %w", file.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("WriteXLSX: %w", err)
+	}
+	return nil
+}
+
+func (w *XLSXWriter) writeDataSheet(out io.Writer, results *KVs, columns []ColumnInfo) error {
+	bw := bufio.NewWriter(out)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	bw.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	bw.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	bw.WriteString(`<selection pane="bottomLeft"/></sheetView></sheetViews>`)
+	bw.WriteString(`<sheetData>`)
+
+	header := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.Code)
+	}
+	if _, err := bw.Write(w.appendRow(w.buf[:0], 1, header, xlsxStyleHeader)); err != nil {
+		return err
+	}
+
+	row := 1
+	err := results.rangeRows(results.GetSortMappingValues(), func(values []interface{}) error {
+		row++
+		w.buf = w.appendRow(w.buf[:0], row, values, xlsxStyleDefault)
+		_, err := bw.Write(w.buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+func (w *XLSXWriter) writeMappingSheet(out io.Writer, columns []ColumnInfo) error {
+	bw := bufio.NewWriter(out)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	w.buf = w.appendRow(w.buf[:0], 1, []interface{}{"path", "code", "type"}, xlsxStyleHeader)
+	for i, c := range columns {
+		w.buf = w.appendRow(w.buf, i+2, []interface{}{c.Path, c.Code, string(c.Type)}, xlsxStyleDefault)
+	}
+	bw.Write(w.buf)
+
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+// appendRow appends the row element of values, nil values leave the cell out
+func (w *XLSXWriter) appendRow(b []byte, row int, values []interface{}, style int) []byte {
+	b = append(b, `<row r="`...)
+	b = strconv.AppendInt(b, int64(row), 10)
+	b = append(b, `">`...)
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		b = w.appendCell(b, xlsxCellRef(i, row), v, style)
+	}
+	return append(b, `</row>`...)
+}
+
+func (w *XLSXWriter) appendCell(b []byte, ref string, v interface{}, style int) []byte {
+	b = append(b, `<c r="`...)
+	b = append(b, ref...)
+	b = append(b, '"')
+
+	if s, ok := v.(string); ok && style == xlsxStyleDefault {
+		if t, ok := w.parseTime(s); ok {
+			v = t
+		}
+	}
+
+	switch v := v.(type) {
+	case bool:
+		b = appendXLSXStyle(b, style)
+		b = append(b, ` t="b"><v>`...)
+		if v {
+			b = append(b, '1')
+		} else {
+			b = append(b, '0')
+		}
+		return append(b, `</v></c>`...)
+	case time.Time:
+		serial, ok := xlsxSerial(v)
+		if !ok {
+			break
+		}
+		b = appendXLSXStyle(b, xlsxStyleTime)
+		b = append(b, `><v>`...)
+		b = strconv.AppendFloat(b, serial, 'f', -1, 64)
+		return append(b, `</v></c>`...)
+	default:
+		if number, ok := xlsxNumber(v); ok {
+			b = appendXLSXStyle(b, style)
+			b = append(b, `><v>`...)
+			b = append(b, number...)
+			return append(b, `</v></c>`...)
+		}
+	}
+
+	b = appendXLSXStyle(b, style)
+	b = append(b, ` t="inlineStr"><is><t`...)
+	s := toString(v)
+	if strings.TrimSpace(s) != s {
+		b = append(b, ` xml:space="preserve"`...)
+	}
+	b = append(b, '>')
+	b = appendXMLText(b, s)
+	return append(b, `</t></is></c>`...)
+}
+
+func (w *XLSXWriter) parseTime(s string) (time.Time, bool) {
+	for _, layout := range w.opts.timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func appendXLSXStyle(b []byte, style int) []byte {
+	if style == xlsxStyleDefault {
+		return b
+	}
+	b = append(b, ` s="`...)
+	b = strconv.AppendInt(b, int64(style), 10)
+	return append(b, '"')
+}
+
+// xlsxNumber returns the text of v as number cell, false if Excel can not hold it exactly
+func xlsxNumber(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v > xlsxMaxExact || v < -xlsxMaxExact {
+			return "", false
+		}
+		return strconv.FormatInt(v, 10), true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true
+	case protoreflect.EnumNumber:
+		return strconv.FormatInt(int64(v), 10), true
+	case uint64:
+		if v > xlsxMaxExact {
+			return "", false
+		}
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case json.Number:
+		if _, err := v.Float64(); err != nil || !isJSONNumber(string(v)) || significantDigits(string(v)) > 15 {
+			return "", false
+		}
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+// significantDigits counts the digits of the mantissa of the JSON number s without
+// leading and trailing zeros, Excel numbers keep 15 of them
+func significantDigits(s string) int {
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimLeft(s, "-")
+	s = strings.Replace(s, ".", "", 1)
+	return len(strings.Trim(s, "0"))
+}
+
+// xlsxSerial returns the Excel serial date of the wall clock of t, false outside the years Excel knows.
+// Excel counts the nonexistent 1900-02-29 as serial 60, so the days before it are one less
+// than the distance to xlsxEpoch, e.g. 1900-01-01 is 1 and 1900-03-01 is 61
+func xlsxSerial(t time.Time) (float64, bool) {
+	if t.Year() < 1900 || t.Year() > 9999 {
+		return 0, false
+	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	seconds := wall.Unix() - xlsxEpoch.Unix()
+	if wall.Before(xlsxMarch1900) {
+		seconds -= 86400
+	}
+	return float64(seconds)/86400 + float64(wall.Nanosecond())/86400e9, true
+}
+
+// xlsxCellRef returns the A1 reference of the zero based column and the one based row
+func xlsxCellRef(column, row int) string {
+	var letters [4]byte
+	i := len(letters)
+	for column++; column > 0; column = (column - 1) / 26 {
+		i--
+		letters[i] = byte('A' + (column-1)%26)
+	}
+	return string(letters[i:]) + strconv.Itoa(row)
+}
+
+// appendXMLText appends s escaped as XML text, characters XML can not hold become U+FFFD
+func appendXMLText(b []byte, s string) []byte {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return append(b, sb.String()...)
+}
+
+func checkSheetNames(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		switch {
+		case name == "":
+			return errors.New("empty sheet name")
+		case len([]rune(name)) > xlsxMaxSheetName:
+			return fmt.Errorf("sheet name %q is longer than %d characters", name, xlsxMaxSheetName)
+		case strings.ContainsAny(name, `[]:*?/\`):
+			return fmt.Errorf("sheet name %q holds one of []:*?/\\", name)
+		case strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'"):
+			return fmt.Errorf("sheet name %q starts or ends with '", name)
+		case seen[strings.ToLower(name)]:
+			return fmt.Errorf("duplicate sheet name %q", name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return nil
+}
+
+func writeXLSXString(w io.Writer, s string) error {
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func writeXLSXContentTypes(w io.Writer, sheets int) error {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	sb.WriteString(`</Types>`)
+	return writeXLSXString(w, sb.String())
+}
+
+func writeXLSXWorkbook(w io.Writer, sheets []string) error {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range sheets {
+		sb.WriteString(`<sheet name="`)
+		xml.EscapeText(&sb, []byte(name))
+		fmt.Fprintf(&sb, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	sb.WriteString(`</sheets></workbook>`)
+	return writeXLSXString(w, sb.String())
+}
+
+func writeXLSXWorkbookRels(w io.Writer, sheets int) error {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	sb.WriteString(`</Relationships>`)
+	return writeXLSXString(w, sb.String())
+}
+
+const xlsxRootRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
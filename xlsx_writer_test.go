@@ -0,0 +1,161 @@
+package struct2csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXWriter_WriteXLSX(t *testing.T) {
+	type row struct {
+		Zip   string
+		Count int64
+		Big   uint64
+		Ok    bool
+		Score float64
+		At    time.Time
+		Raw   json.Number
+		Note  string
+	}
+	at := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	data := []row{
+		{Zip: "00123", Count: 7, Big: 1 << 60, Ok: true, Score: 0.5, At: at, Raw: "12345678901234567890", Note: " a<b "},
+		{Zip: "2024-01-02T12:00:00Z", Raw: "1.25"},
+	}
+
+	conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+	kvs, err := conv.Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	var b bytes.Buffer
+	err = NewXLSXWriter(&b, WithXLSXMappingSheet("mapping"), WithXLSXTimeLayouts(time.RFC3339)).WriteXLSX(kvs)
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	files := readTestZip(t, b.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("WriteXLSX() missing %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="data" sheetId="1" r:id="rId1"/><sheet name="mapping" sheetId="2" r:id="rId2"/>`) {
+		t.Errorf("WriteXLSX() workbook = %s", files["xl/workbook.xml"])
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	wants := []string{
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`,
+		`<c r="A1" s="1" t="inlineStr"><is><t>/At</t></is></c>`,
+		`<c r="A2" s="2"><v>45293.5</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t>1152921504606846976</t></is></c>`,
+		`<c r="C2"><v>7</v></c>`,
+		`<c r="D2" t="inlineStr"><is><t xml:space="preserve"> a&lt;b </t></is></c>`,
+		`<c r="E2" t="b"><v>1</v></c>`,
+		`<c r="F2" t="inlineStr"><is><t>12345678901234567890</t></is></c>`,
+		`<c r="G2"><v>0.5</v></c>`,
+		`<c r="H2" t="inlineStr"><is><t>00123</t></is></c>`,
+		`<row r="3"><c r="F3"><v>1.25</v></c><c r="H3" s="2"><v>45293.5</v></c></row>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("WriteXLSX() sheet1 misses %s\n%s", want, sheet)
+		}
+	}
+
+	mapping := files["xl/worksheets/sheet2.xml"]
+	if !strings.Contains(mapping, `<row r="2"><c r="A2" t="inlineStr"><is><t>/At</t></is></c><c r="B2" t="inlineStr"><is><t>/At</t></is></c><c r="C2" t="inlineStr"><is><t>time</t></is></c></row>`) {
+		t.Errorf("WriteXLSX() sheet2 = %s", mapping)
+	}
+}
+
+func TestXLSXWriter_SheetName(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []XLSXOption
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "invalid character", opts: []XLSXOption{WithXLSXSheetName("a/b")}, wantErr: true},
+		{name: "too long", opts: []XLSXOption{WithXLSXSheetName(strings.Repeat("a", 32))}, wantErr: true},
+		{name: "duplicate", opts: []XLSXOption{WithXLSXMappingSheet("DATA")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, _ := NewStructConverter(NewHeaderOriginalStringConv())
+			kvs, _ := conv.Convert([]struct{ A int }{{A: 1}})
+			err := NewXLSXWriter(io.Discard, tt.opts...).WriteXLSX(kvs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteXLSX() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_xlsxCellRef(t *testing.T) {
+	tests := []struct {
+		column int
+		row    int
+		want   string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{701, 4, "ZZ4"},
+		{702, 5, "AAA5"},
+		{16383, 6, "XFD6"},
+	}
+	for _, tt := range tests {
+		if got := xlsxCellRef(tt.column, tt.row); got != tt.want {
+			t.Errorf("xlsxCellRef(%d, %d) = %v, want %v", tt.column, tt.row, got, tt.want)
+		}
+	}
+}
+
+func Test_xlsxSerial(t *testing.T) {
+	tests := []struct {
+		t      time.Time
+		want   float64
+		wantOk bool
+	}{
+		{time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC), 0, false},
+		{time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), 1, true},
+		{time.Date(1900, 1, 1, 12, 0, 0, 0, time.UTC), 1.5, true},
+		{time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC), 59, true},
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 61, true},
+		{time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), 45293.25, true},
+		{time.Date(2024, 1, 2, 6, 0, 0, 0, time.FixedZone("JST", 9*60*60)), 45293.25, true},
+	}
+	for _, tt := range tests {
+		got, ok := xlsxSerial(tt.t)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("xlsxSerial(%v) = %v, %v, want %v, %v", tt.t, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func readTestZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	files := make(map[string]string, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}